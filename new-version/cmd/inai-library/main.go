@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"new-version/internal/app"
 	"new-version/internal/config"
 	httpserver "new-version/internal/http/server"
+	"new-version/internal/metrics"
+	reservationRepo "new-version/internal/repository/reservation"
 	"new-version/internal/storage/postgres"
+	"new-version/internal/tracing"
 	"new-version/pkg/logger"
 	"os"
-	"time"
 )
 
 // @title INAI Library API
@@ -16,46 +19,56 @@ import (
 // @description API Server for library application of the university INAI
 
 // @host localhost:8080
-// @BasePath
+// @BasePath /api/v2
 
 func main() {
 	cfg := config.MustLoad()
 
-	storage, err := postgres.New(&cfg.Database)
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	log := logger.SetupLogger(cfg.Env)
+	slog.SetDefault(log)
 
-	done := make(chan os.Signal, 1)
+	shutdownTracing, err := tracing.Setup(context.Background(), &cfg.Tracing)
+	if err != nil {
+		log.Error("failed to set up tracing", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	srv := httpserver.New(log, cfg, storage)
+	storage, err := postgres.New(&cfg.Database)
+	if err != nil {
+		log.Error("failed to open storage", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error("failed to start server")
-		}
-	}()
+	application := app.New(log, cfg.ShutdownTimeout)
 
-	log.Info("server started")
+	// closed in reverse order, the database last
+	application.OnClose("database", func(context.Context) error { return storage.DB().Close() })
+	application.OnClose("tracing", shutdownTracing)
 
-	<-done
+	m := metrics.New(log, storage.DB(), reservationRepo.New(storage.DB()))
 
-	log.Info("stop server")
+	srv, err := httpserver.New(log, cfg, storage, m, application)
+	if err != nil {
+		log.Error("failed to create server", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	application.AddServer("http", srv)
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("failed to stop server: ", err)
+	if cfg.Admin.Enabled {
+		admin, err := httpserver.NewAdmin(log, cfg, m, application)
+		if err != nil {
+			log.Error("failed to create admin server", slog.String("error", err.Error()))
+			os.Exit(1)
+		}
 
-		return
+		application.AddServer("admin", admin)
 	}
 
-	defer storage.DB().Close()
-
-	log.Info("server stopped")
+	if err := application.Run(context.Background()); err != nil {
+		log.Error("server stopped with errors", slog.String("error", err.Error()))
+		os.Exit(1)
+	}
 
 	// TODO: Add other entities
 	// TODO: Add business logic
@@ -77,4 +77,72 @@ CREATE TABLE IF NOT EXISTS notifications(
 
     CONSTRAINT fk_user FOREIGN KEY (recipient_id) REFERENCES users(id) 
     ON DELETE SET NULL ON UPDATE CASCADE
-); 
\ No newline at end of file
+); 
+
+CREATE TABLE IF NOT EXISTS audit_events(
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    action VARCHAR(100) NOT NULL,
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    outcome VARCHAR(20) NOT NULL,
+    details TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor);
+
+-- audit records are append-only
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER trg_audit_events_append_only
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TABLE IF NOT EXISTS sessions(
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NULL,
+
+    CONSTRAINT fk_user FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- account deletion: users are purged after a grace period, their reviews are kept anonymized
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deletion_requested_at TIMESTAMP NULL;
+ALTER TABLE reviews ALTER COLUMN author_id DROP NOT NULL;
+
+-- admins acting on behalf of a user through an impersonation session
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS impersonator VARCHAR(255) NOT NULL DEFAULT '';
+
+-- optimistic concurrency: writes name the version they expect, every write bumps it
+ALTER TABLE book_categories ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+-- category titles are unique, duplicates of an older title get their id appended
+UPDATE book_categories c SET title = left(c.title, 240) || ' (' || c.id || ')'
+WHERE EXISTS (SELECT 1 FROM book_categories d WHERE d.title = c.title AND d.id < c.id);
+ALTER TABLE book_categories DROP CONSTRAINT IF EXISTS uq_book_categories_title;
+ALTER TABLE book_categories ADD CONSTRAINT uq_book_categories_title UNIQUE (title);
+
+-- version of this file, checked by /readyz against postgres.SchemaVersion;
+-- bump both when changing the schema
+CREATE TABLE IF NOT EXISTS schema_version(
+    version INT NOT NULL
+);
+
+INSERT INTO schema_version(version) SELECT 0 WHERE NOT EXISTS (SELECT 1 FROM schema_version);
+UPDATE schema_version SET version = 3;
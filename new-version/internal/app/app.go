@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type server struct {
+	name string
+	srv  *http.Server
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// App runs the HTTP servers and background workers of the process until
+// SIGINT or SIGTERM, then shuts everything down in order: servers drain
+// their in-flight requests, workers stop in reverse order of registration,
+// and closers run last, also in reverse order.
+type App struct {
+	log             *slog.Logger
+	shutdownTimeout time.Duration
+
+	servers []server
+	workers []worker
+	closers []closer
+}
+
+func New(log *slog.Logger, shutdownTimeout time.Duration) *App {
+	return &App{log: log, shutdownTimeout: shutdownTimeout}
+}
+
+func (a *App) AddServer(name string, srv *http.Server) {
+	a.servers = append(a.servers, server{name: name, srv: srv})
+}
+
+// AddWorker registers run to be called in its own goroutine. run must
+// return once ctx is done.
+func (a *App) AddWorker(name string, run func(ctx context.Context)) {
+	a.workers = append(a.workers, worker{name: name, run: run})
+}
+
+// OnClose registers a resource to release after servers and workers have
+// stopped. Register the ones others depend on, like the database, first.
+func (a *App) OnClose(name string, close func(ctx context.Context) error) {
+	a.closers = append(a.closers, closer{name: name, close: close})
+}
+
+// Run starts everything and blocks until a signal arrives, ctx is done or a
+// server fails. It returns the errors of the failed server and of the
+// shutdown.
+func (a *App) Run(ctx context.Context) error {
+	const op = "app.Run"
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, len(a.servers))
+
+	for _, s := range a.servers {
+		go func() {
+			tls := s.srv.TLSConfig != nil
+			a.log.Info("server started", slog.String("server", s.name), slog.String("address", s.srv.Addr), slog.Bool("tls", tls))
+
+			var err error
+			if tls {
+				// the certificate comes from TLSConfig
+				err = s.srv.ListenAndServeTLS("", "")
+			} else {
+				err = s.srv.ListenAndServe()
+			}
+
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("%s: server %s: %w", op, s.name, err)
+			}
+		}()
+	}
+
+	type running struct {
+		name   string
+		cancel context.CancelFunc
+		done   chan struct{}
+	}
+
+	workers := make([]running, 0, len(a.workers))
+
+	for _, w := range a.workers {
+		// workers outlive the signal until the servers have drained
+		wctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		done := make(chan struct{})
+
+		go func() {
+			defer close(done)
+			w.run(wctx)
+		}()
+
+		workers = append(workers, running{name: w.name, cancel: cancel, done: done})
+	}
+
+	var runErr error
+
+	select {
+	case <-ctx.Done():
+		a.log.Info("shutting down")
+	case runErr = <-failed:
+		a.log.Error("shutting down after server failure", slog.String("error", runErr.Error()))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	errs := []error{runErr}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	for _, s := range a.servers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if err := s.srv.Shutdown(shutdownCtx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: stop server %s: %w", op, s.name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	for i := len(workers) - 1; i >= 0; i-- {
+		w := workers[i]
+		w.cancel()
+
+		select {
+		case <-w.done:
+		case <-shutdownCtx.Done():
+			errs = append(errs, fmt.Errorf("%s: stop worker %s: %w", op, w.name, shutdownCtx.Err()))
+		}
+	}
+
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		c := a.closers[i]
+
+		if err := c.close(shutdownCtx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: close %s: %w", op, c.name, err))
+		}
+	}
+
+	a.log.Info("stopped")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Commit and Time are set when building release binaries:
+//
+//	go build -ldflags "-X new-version/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//		-X new-version/internal/buildinfo.Time=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/inai-library
+//
+// Otherwise they're taken from the VCS info the go command embeds.
+var (
+	Commit string
+	Time   string
+)
+
+type Info struct {
+	Commit    string
+	Time      string
+	Modified  bool
+	GoVersion string
+}
+
+func Get() Info {
+	info := Info{Commit: Commit, Time: Time, GoVersion: runtime.Version()}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = s.Value
+			}
+		case "vcs.time":
+			if info.Time == "" {
+				info.Time = s.Value
+			}
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+
+	return info
+}
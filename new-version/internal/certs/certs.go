@@ -0,0 +1,165 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"new-version/internal/config"
+)
+
+var ErrNoCertificates = errors.New("no certificates found")
+
+// Reloader serves a certificate key pair and loads it again once the files
+// change, so certificates can be rotated without restarting the server.
+type Reloader struct {
+	log      *slog.Logger
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func NewReloader(log *slog.Logger, certFile string, keyFile string) (*Reloader, error) {
+	const op = "certs.NewReloader"
+
+	r := &Reloader{log: log, certFile: certFile, keyFile: keyFile}
+
+	if _, err := r.Reload(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return r, nil
+}
+
+// GetCertificate is meant for tls.Config.GetCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// Reload loads the key pair if either file was modified since the last load
+// and reports whether it did. On error the current certificate is kept.
+func (r *Reloader) Reload() (bool, error) {
+	const op = "certs.Reloader.Reload"
+
+	modTime, err := r.lastModified()
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && modTime.Equal(r.modTime)
+	r.mu.RUnlock()
+
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", op, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+func (r *Reloader) lastModified() (time.Time, error) {
+	var last time.Time
+
+	for _, name := range []string{r.certFile, r.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if fi.ModTime().After(last) {
+			last = fi.ModTime()
+		}
+	}
+
+	return last, nil
+}
+
+// Watch returns a worker checking the files every interval until ctx is
+// done, see app.App.AddWorker.
+func (r *Reloader) Watch(interval time.Duration) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloaded, err := r.Reload()
+				if err != nil {
+					r.log.Error("failed to reload certificate", slog.String("error", err.Error()))
+					continue
+				}
+
+				if reloaded {
+					r.log.Info("reloaded certificate", slog.String("cert_file", r.certFile))
+				}
+			}
+		}
+	}
+}
+
+// ServerConfig returns the TLS config of a server presenting the
+// certificate of r.
+func ServerConfig(cfg *config.TLS, r *Reloader) (*tls.Config, error) {
+	const op = "certs.ServerConfig"
+
+	version, err := cfg.Version()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &tls.Config{
+		MinVersion:     version,
+		GetCertificate: r.GetCertificate,
+	}, nil
+}
+
+// MutualConfig is ServerConfig that also requires clients to present a
+// certificate signed by one of the CAs in clientCAFile.
+func MutualConfig(cfg *config.TLS, r *Reloader, clientCAFile string) (*tls.Config, error) {
+	const op = "certs.MutualConfig"
+
+	tlsCfg, err := ServerConfig(cfg, r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pem, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: %w", op, ErrNoCertificates)
+	}
+
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+
+	return tlsCfg, nil
+}
@@ -1,14 +1,25 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/spf13/viper"
 )
 
+const (
+	EnvLocal = "local"
+	EnvProd  = "prod"
+)
+
 type Config struct {
 	Env         string `yaml:"env" env-default:"local"`
 	StoragePath string `yaml:"storage_path" env-required:"true"`
@@ -16,6 +27,28 @@ type Config struct {
 	Pagination  `yaml:"pagination"`
 	Security    `yaml:"security"`
 	Database    `yaml:"database"`
+	Privacy     `yaml:"privacy"`
+	Admin       Admin     `yaml:"admin"`
+	Tracing     Tracing   `yaml:"tracing"`
+	RateLimit   RateLimit `yaml:"rate_limit"`
+	API         API       `yaml:"api"`
+}
+
+// API configures the versions served. The Go API lives under /api/v2,
+// Unversioned also serves it without the prefix, as it was before
+// versioning, until its sunset. V1 serves the paths and response shapes of
+// the Django API for the old front-end.
+type API struct {
+	Unversioned Lifecycle `yaml:"unversioned"`
+	V1          Lifecycle `yaml:"v1"`
+}
+
+// Lifecycle schedules the removal of an API version, which is served until
+// it's disabled. Dates are RFC 3339, zero ones aren't announced.
+type Lifecycle struct {
+	Enabled    bool      `yaml:"enabled"`
+	Deprecated time.Time `yaml:"deprecated"`
+	Sunset     time.Time `yaml:"sunset"`
 }
 
 type Database struct {
@@ -30,6 +63,152 @@ type HTTPServer struct {
 	Address     string        `yaml:"address" env-default:"localhost:8000"`
 	Timeout     time.Duration `yaml:"timeout" env-default:"4s"`
 	IdleTimeout time.Duration `yaml:"idle-timeout" env-default:"60s"`
+	// ShutdownTimeout bounds draining requests and stopping workers
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env-default:"15s"`
+	AccessLog       AccessLog     `yaml:"access_log"`
+	CORS            CORS          `yaml:"cors"`
+	Cookie          Cookie        `yaml:"cookie"`
+	TLS             TLS           `yaml:"tls"`
+	Cache           Cache         `yaml:"cache"`
+}
+
+// Cache configures how long browsers and proxies may reuse catalog reads
+// without revalidating them. 0 makes them revalidate every time, which is
+// cheap with ETags.
+type Cache struct {
+	CatalogMaxAge time.Duration `yaml:"catalog_max_age"`
+}
+
+// TLS configures HTTPS. MinVersion is "1.2" or "1.3". The certificate and
+// key files are checked for changes every ReloadInterval, so renewed
+// certificates are picked up without a restart.
+type TLS struct {
+	Enabled        bool          `yaml:"enabled" env-default:"false"`
+	CertFile       string        `yaml:"cert_file"`
+	KeyFile        string        `yaml:"key_file"`
+	MinVersion     string        `yaml:"min_version" env-default:"1.2"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env-default:"1m"`
+}
+
+func (t *TLS) Version() (uint16, error) {
+	switch t.MinVersion {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+
+	return 0, fmt.Errorf("unsupported tls min_version %q", t.MinVersion)
+}
+
+// CORS lists what cross-origin browser requests may do. MaxAge is how long
+// browsers cache the preflight response.
+type CORS struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env-default:"http://localhost:3000"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env-default:"GET,POST,PUT,PATCH,DELETE"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env-default:"Content-Type,Authorization,X-CSRF-Token,X-Request-ID,If-None-Match,If-Modified-Since,If-Match"`
+	AllowCredentials bool          `yaml:"allow_credentials"`
+	MaxAge           time.Duration `yaml:"max_age" env-default:"10m"`
+}
+
+// Cookie configures the access_token and csrf_token cookies. SameSite is
+// "lax", "strict" or "none", the latter requires Secure. A MaxAge of 0 keeps
+// the access_token cookie as long as the token is valid.
+type Cookie struct {
+	Domain   string        `yaml:"domain"`
+	Secure   bool          `yaml:"secure"`
+	SameSite string        `yaml:"same_site" env-default:"lax"`
+	MaxAge   time.Duration `yaml:"max_age" env-default:"0s"`
+}
+
+func (c *Cookie) SameSiteMode() (http.SameSite, error) {
+	switch strings.ToLower(c.SameSite) {
+	case "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	}
+
+	return 0, fmt.Errorf("unknown same_site mode %q", c.SameSite)
+}
+
+// AccessLog configures the request log. Only SampleRate (0 to 1) of the
+// successful requests are logged, failed requests and requests slower than
+// SlowThreshold always are.
+type AccessLog struct {
+	SampleRate    float64       `yaml:"sample_rate"`
+	SlowThreshold time.Duration `yaml:"slow_threshold" env-default:"1s"`
+}
+
+// Admin configures the listener of operational endpoints like /metrics and
+// pprof, which is off unless Enabled. It must only be reachable from the
+// internal network. Clients must present a certificate signed by
+// ClientCAFile, so TLS is required in every env but local.
+type Admin struct {
+	Enabled      bool   `yaml:"enabled"`
+	Address      string `yaml:"address" env-default:"localhost:9090"`
+	TLS          TLS    `yaml:"tls"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is "stdout" for local debugging
+// or "otlp" to send spans over HTTP to Endpoint (host:port of a collector).
+// SampleRatio applies to traces that don't come with a sampling decision.
+type Tracing struct {
+	Enabled     bool    `yaml:"enabled" env-default:"false"`
+	Exporter    string  `yaml:"exporter" env-default:"stdout"`
+	Endpoint    string  `yaml:"endpoint" env-default:"localhost:4318"`
+	Insecure    bool    `yaml:"insecure" env-default:"false"`
+	SampleRatio float64 `yaml:"sample_ratio" env-default:"1"`
+	ServiceName string  `yaml:"service_name" env-default:"inai-library"`
+}
+
+// RateLimit configures token buckets holding up to *Burst requests and
+// refilled at *PerMinute requests a minute. Every request is limited first:
+// by client address with IP when it has no valid access token, by user with
+// User when it has. Authenticated routes are then limited by user per route
+// group, with the rates in Groups or User for groups not listed there. Auth
+// applies to login and registration. X-Forwarded-For is only trusted from
+// TrustedProxies (IPs or CIDR prefixes). Every limiter keeps the buckets of
+// at most MaxKeys clients.
+type RateLimit struct {
+	Enabled        bool            `yaml:"enabled"`
+	TrustedProxies []string        `yaml:"trusted_proxies"`
+	MaxKeys        int             `yaml:"max_keys" env-default:"100000"`
+	IPPerMinute    int             `yaml:"ip_per_minute" env-default:"300"`
+	IPBurst        int             `yaml:"ip_burst" env-default:"60"`
+	UserPerMinute  int             `yaml:"user_per_minute" env-default:"600"`
+	UserBurst      int             `yaml:"user_burst" env-default:"100"`
+	AuthPerMinute  int             `yaml:"auth_per_minute" env-default:"10"`
+	AuthBurst      int             `yaml:"auth_burst" env-default:"5"`
+	Groups         map[string]Rate `yaml:"groups"`
+}
+
+type Rate struct {
+	PerMinute int `yaml:"per_minute"`
+	Burst     int `yaml:"burst"`
+}
+
+// RateLimitGroups are the route groups whose rates can be set in
+// rate_limit.groups.
+var RateLimitGroups = []string{"account", "audit", "book_category", "impersonation", "legacy", "user"}
+
+// Group returns the rate of the route group name.
+func (r *RateLimit) Group(name string) Rate {
+	if rate, ok := r.Groups[name]; ok {
+		return rate
+	}
+
+	return Rate{PerMinute: r.UserPerMinute, Burst: r.UserBurst}
+}
+
+// Privacy configures account deletion. Accounts are purged once
+// DeletionGracePeriod has passed since the user asked for deletion.
+type Privacy struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" env-default:"720h"`
+	PurgeInterval       time.Duration `yaml:"purge_interval" env-default:"1h"`
 }
 
 type Pagination struct {
@@ -39,10 +218,60 @@ type Pagination struct {
 }
 
 type Security struct {
-	PasswordMinLen     int           `yaml:"password_min_len"`
-	JwtSecret          string        `yaml:"jwt_secret"`
-	AccessTokenExpire  time.Duration `yaml:"access_token_expire"`
-	RefreshTokenExpire time.Duration `yaml:"refresh_token_expire"`
+	// PasswordMinLen is the key password_policy.min_len replaced. Migrate
+	// still honors it.
+	PasswordMinLen      int            `yaml:"password_min_len"`
+	JwtSecret           string         `yaml:"jwt_secret"`
+	AccessTokenExpire   time.Duration  `yaml:"access_token_expire"`
+	RefreshTokenExpire  time.Duration  `yaml:"refresh_token_expire"`
+	ImpersonationExpire time.Duration  `yaml:"impersonation_expire" env-default:"30m"`
+	PasswordPolicy      PasswordPolicy `yaml:"password_policy"`
+	LDAP                LDAP           `yaml:"ldap"`
+	Hashing             Hashing        `yaml:"hashing"`
+}
+
+// PasswordPolicy lists the rules for new passwords. Lengths are counted in
+// characters, MaxRepeated of 0 allows any run of the same character. Every
+// rule is on unless the config turns it off.
+// BreachedListPath points to a file with one leaked password or SHA-1 hash
+// (as in the Have I Been Pwned dumps) per line.
+type PasswordPolicy struct {
+	MinLen             int    `yaml:"min_len"`
+	MaxLen             int    `yaml:"max_len" env-default:"64"`
+	RequireUpper       bool   `yaml:"require_upper"`
+	RequireLower       bool   `yaml:"require_lower"`
+	RequireDigit       bool   `yaml:"require_digit"`
+	RequireSpecial     bool   `yaml:"require_special"`
+	MaxRepeated        int    `yaml:"max_repeated"`
+	RejectEmailSimilar bool   `yaml:"reject_email_similar"`
+	BreachedListPath   string `yaml:"breached_list_path"`
+}
+
+// Hashing selects the algorithm for new password hashes. Argon2Memory is in KiB.
+type Hashing struct {
+	Algorithm         string `yaml:"algorithm" env-default:"bcrypt"`
+	BcryptCost        int    `yaml:"bcrypt_cost" env-default:"10"`
+	Argon2Memory      uint32 `yaml:"argon2_memory" env-default:"65536"`
+	Argon2Iterations  uint32 `yaml:"argon2_iterations" env-default:"3"`
+	Argon2Parallelism uint8  `yaml:"argon2_parallelism" env-default:"2"`
+	Argon2SaltLength  uint32 `yaml:"argon2_salt_length" env-default:"16"`
+	Argon2KeyLength   uint32 `yaml:"argon2_key_length" env-default:"32"`
+}
+
+// LDAP configures the directory authenticator used for staff accounts.
+// GroupRoles maps group DNs found in GroupAttribute to access levels.
+type LDAP struct {
+	Enabled        bool           `yaml:"enabled" env-default:"false"`
+	URL            string         `yaml:"url"`
+	StartTLS       bool           `yaml:"start_tls" env-default:"false"`
+	BindDN         string         `yaml:"bind_dn"`
+	BindPassword   string         `yaml:"bind_password"`
+	BaseDN         string         `yaml:"base_dn"`
+	UserFilter     string         `yaml:"user_filter" env-default:"(mail=%s)"`
+	GroupAttribute string         `yaml:"group_attribute" env-default:"memberOf"`
+	GroupRoles     map[string]int `yaml:"group_roles"`
+	DefaultLevel   int            `yaml:"default_level" env-default:"50"`
+	Timeout        time.Duration  `yaml:"timeout" env-default:"5s"`
 }
 
 func MustLoad() *Config {
@@ -55,13 +284,176 @@ func MustLoad() *Config {
 		log.Fatalf("config file doesn't exist: %s", configPath)
 	}
 
-	var cfg Config
-
-	if err := cleanenv.ReadConfig(configPath, &cfg); err != nil {
+	cfg, err := Load(configPath)
+	if err != nil {
 		log.Fatalf("error during config reading: %v", err)
 	}
 
-	return &cfg
+	if cfg.Security.PasswordMinLen > 0 {
+		log.Print("security.password_min_len is deprecated, use security.password_policy.min_len")
+	}
+
+	return cfg
+}
+
+// Load reads the config file at path and the environment, then migrates and
+// validates the result.
+func Load(path string) (*Config, error) {
+	const op = "config.Load"
+
+	cfg := defaults()
+
+	if err := cleanenv.ReadConfig(path, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := cfg.Migrate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &cfg, nil
+}
+
+// defaults returns the defaults of settings for which false or 0 is a valid
+// choice. cleanenv applies env-default to every zero value after reading the
+// file, so such a tag would override an explicit false or 0.
+func defaults() Config {
+	return Config{
+		HTTPServer: HTTPServer{
+			AccessLog: AccessLog{SampleRate: 1},
+			CORS:      CORS{AllowCredentials: true},
+			Cookie:    Cookie{Secure: true},
+			Cache:     Cache{CatalogMaxAge: time.Minute},
+		},
+		Security: Security{
+			PasswordPolicy: PasswordPolicy{
+				RequireUpper:       true,
+				RequireLower:       true,
+				RequireDigit:       true,
+				RequireSpecial:     true,
+				MaxRepeated:        3,
+				RejectEmailSimilar: true,
+			},
+		},
+		RateLimit: RateLimit{Enabled: true},
+		API: API{
+			Unversioned: Lifecycle{Enabled: true},
+			V1:          Lifecycle{Enabled: true},
+		},
+	}
+}
+
+const DefaultPasswordMinLen = 8
+
+// Migrate moves the values of renamed keys to their current place and fills
+// defaults that depend on them. Setting both the old and the new key to
+// different values is an error.
+func (c *Config) Migrate() error {
+	const op = "config.Migrate"
+
+	policy := &c.Security.PasswordPolicy
+
+	if c.Security.PasswordMinLen > 0 {
+		if policy.MinLen > 0 && policy.MinLen != c.Security.PasswordMinLen {
+			return fmt.Errorf("%s: security: password_min_len is replaced by password_policy.min_len, set only the latter", op)
+		}
+
+		policy.MinLen = c.Security.PasswordMinLen
+	}
+
+	if policy.MinLen == 0 {
+		policy.MinLen = DefaultPasswordMinLen
+	}
+
+	return nil
+}
+
+var ErrWildcardCredentials = errors.New("cors: wildcard origin with credentials is not allowed in prod")
+
+// Validate reports settings that can't work together or are unsafe in
+// production.
+func (c *Config) Validate() error {
+	const op = "config.Validate"
+
+	mode, err := c.Cookie.SameSiteMode()
+	if err != nil {
+		return fmt.Errorf("%s: cookie: %w", op, err)
+	}
+
+	if mode == http.SameSiteNoneMode && !c.Cookie.Secure {
+		return fmt.Errorf("%s: cookie: same_site none requires secure", op)
+	}
+
+	if err := c.HTTPServer.TLS.validate(); err != nil {
+		return fmt.Errorf("%s: http_server: %w", op, err)
+	}
+
+	if err := c.Admin.TLS.validate(); err != nil {
+		return fmt.Errorf("%s: admin: %w", op, err)
+	}
+
+	if c.Admin.TLS.Enabled && c.Admin.ClientCAFile == "" {
+		return fmt.Errorf("%s: admin: tls requires client_ca_file", op)
+	}
+
+	// pprof and metrics tell too much to be served without client certificates
+	if c.Admin.Enabled && !c.Admin.TLS.Enabled && c.Env != EnvLocal {
+		return fmt.Errorf("%s: admin: tls with client_ca_file is required outside %s", op, EnvLocal)
+	}
+
+	for name := range c.RateLimit.Groups {
+		if !slices.Contains(RateLimitGroups, name) {
+			return fmt.Errorf("%s: rate_limit: unknown group %q, expected one of %s", op, name, strings.Join(RateLimitGroups, ", "))
+		}
+	}
+
+	if err := c.API.Unversioned.validate(); err != nil {
+		return fmt.Errorf("%s: api: unversioned: %w", op, err)
+	}
+
+	if err := c.API.V1.validate(); err != nil {
+		return fmt.Errorf("%s: api: v1: %w", op, err)
+	}
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		return fmt.Errorf("%s: cors: no allowed origins", op)
+	}
+
+	if c.Env == EnvProd && c.CORS.AllowCredentials && slices.Contains(c.CORS.AllowedOrigins, "*") {
+		return fmt.Errorf("%s: %w", op, ErrWildcardCredentials)
+	}
+
+	return nil
+}
+
+func (l *Lifecycle) validate() error {
+	if !l.Deprecated.IsZero() && !l.Sunset.IsZero() && l.Sunset.Before(l.Deprecated) {
+		return errors.New("sunset is before deprecation")
+	}
+
+	return nil
+}
+
+func (t *TLS) validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("tls requires cert_file and key_file")
+	}
+
+	if t.ReloadInterval <= 0 {
+		return errors.New("tls reload_interval must be positive")
+	}
+
+	_, err := t.Version()
+
+	return err
 }
 
 // load config using viper
@@ -0,0 +1,45 @@
+package account
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"new-version/internal/contract/user"
+)
+
+type Reservation struct {
+	Id           uuid.UUID  `json:"id"`
+	BookId       int        `json:"book_id"`
+	Quantity     int        `json:"quantity"`
+	Status       string     `json:"status"`
+	ReservedAt   time.Time  `json:"reserved_at"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+	ReturnedDate *time.Time `json:"returned_date,omitempty"`
+}
+
+type Review struct {
+	Id     int  `json:"id"`
+	BookId int  `json:"book_id"`
+	Rating *int `json:"rating,omitempty"`
+}
+
+type Notification struct {
+	Id      int    `json:"id"`
+	Title   string `json:"title"`
+	Message string `json:"message"`
+}
+
+// Export is everything the library stores about a user.
+type Export struct {
+	GeneratedAt   time.Time         `json:"generated_at"`
+	Profile       user.InfoResponse `json:"profile"`
+	Reservations  []Reservation     `json:"reservations"`
+	Reviews       []Review          `json:"reviews"`
+	Notifications []Notification    `json:"notifications"`
+}
+
+type Deletion struct {
+	RequestedAt time.Time `json:"requested_at"`
+	PurgeAfter  time.Time `json:"purge_after"`
+}
@@ -0,0 +1,29 @@
+package audit
+
+import "time"
+
+type Event struct {
+	Id         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	// Impersonator is the admin acting on behalf of Actor, if any.
+	Impersonator string `json:"impersonator,omitempty"`
+	Action       string `json:"action"`
+	Target       string `json:"target"`
+	IP           string `json:"ip"`
+	UserAgent    string `json:"user_agent"`
+	Outcome      string `json:"outcome"`
+	Details      string `json:"details,omitempty"`
+}
+
+type Filter struct {
+	Actor        string
+	Impersonator string
+	Action       string
+	Target       string
+	Outcome      string
+	From         time.Time
+	To           time.Time
+	Limit        int
+	Offset       int
+}
@@ -3,11 +3,13 @@ package bookcategory
 import "time"
 
 type Request struct {
-	Title string `json:"title"`
+	Title string `json:"title" validate:"required,max=255"`
 }
 
 type Response struct {
 	Id          int       `json:"id"`
 	Title       string    `json:"title"`
 	CreatedTime time.Time `json:"created_time"`
+	UpdatedTime time.Time `json:"updated_time"`
+	Version     int       `json:"version"`
 }
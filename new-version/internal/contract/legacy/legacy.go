@@ -0,0 +1,47 @@
+package legacy
+
+import "github.com/google/uuid"
+
+// The shapes of the Django API (/api/v1) the old front-end still reads.
+
+type Message struct {
+	Message string `json:"Сообщение"`
+}
+
+type Credentials struct {
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+}
+
+// AuthResponse answers registration and login. There are no refresh tokens
+// anymore, RefreshToken carries the access token so that logout, which
+// sends it back, keeps working.
+type AuthResponse struct {
+	Message      string `json:"Сообщение"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	User         User   `json:"user"`
+}
+
+type User struct {
+	Id    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Role  string    `json:"role"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Category is shown with its version, which clients that can't send If-Match
+// return with their changes.
+type Category struct {
+	Id      int    `json:"id"`
+	Title   string `json:"title"`
+	Version int    `json:"version"`
+}
+
+type CategoryRequest struct {
+	Title   string `json:"title" validate:"required,max=255"`
+	Version int    `json:"version"`
+}
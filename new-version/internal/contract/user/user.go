@@ -15,8 +15,8 @@ type Model struct {
 }
 
 type Request struct {
-	Email    string `json:"email"`
-	Password string `json:"pass_hash"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"pass_hash" validate:"required"`
 }
 
 type Response struct {
@@ -0,0 +1,71 @@
+package cookie
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"new-version/internal/config"
+)
+
+// Jar sets the cookies of the API with the domain, Secure and SameSite
+// attributes from the config.
+type Jar struct {
+	domain   string
+	secure   bool
+	sameSite http.SameSite
+	maxAge   time.Duration
+}
+
+func New(cfg *config.Cookie) (*Jar, error) {
+	const op = "http.cookie.New"
+
+	mode, err := cfg.SameSiteMode()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return &Jar{
+		domain:   cfg.Domain,
+		secure:   cfg.Secure,
+		sameSite: mode,
+		maxAge:   cfg.MaxAge,
+	}, nil
+}
+
+// Set sets an HttpOnly cookie living for the configured max age or, if none
+// is configured, for ttl. A ttl of 0 makes a session cookie.
+func (j *Jar) Set(w http.ResponseWriter, name string, value string, ttl time.Duration) {
+	if j.maxAge > 0 {
+		ttl = j.maxAge
+	}
+
+	c := j.cookie(name, value)
+	if ttl > 0 {
+		c.MaxAge = int(ttl.Seconds())
+		c.Expires = time.Now().Add(ttl)
+	}
+
+	http.SetCookie(w, c)
+}
+
+// Clear tells the browser to drop the cookie.
+func (j *Jar) Clear(w http.ResponseWriter, name string) {
+	c := j.cookie(name, "")
+	c.MaxAge = -1
+	c.Expires = time.Unix(0, 0)
+
+	http.SetCookie(w, c)
+}
+
+func (j *Jar) cookie(name string, value string) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   j.domain,
+		HttpOnly: true,
+		Secure:   j.secure,
+		SameSite: j.sameSite,
+	}
+}
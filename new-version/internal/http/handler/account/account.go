@@ -0,0 +1,211 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	stdJson "encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	accountDto "new-version/internal/contract/account"
+	"time"
+
+	mwAuth "new-version/internal/http/middleware/auth"
+	"new-version/internal/http/router"
+
+	accountSvc "new-version/internal/service/account"
+
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+)
+
+type Handler interface {
+	ExportData(w http.ResponseWriter, r *http.Request)
+	DeleteAccount(w http.ResponseWriter, r *http.Request)
+	RestoreAccount(w http.ResponseWriter, r *http.Request)
+}
+
+type DefaultHandler struct {
+	log *slog.Logger
+	svc accountSvc.Service
+}
+
+func New(log *slog.Logger, svc accountSvc.Service) *DefaultHandler {
+	return &DefaultHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+// RegisterRoutes adds the routes to rt, protected by the shared
+// middlewares in mw.
+func (a *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	me := rt.Group(mw.Auth.Require(hp.USER_LVL), mw.RateLimit, mw.Auth.NoImpersonation)
+	me.HandleFunc("GET /user/me/export", a.ExportData)
+	me.HandleFunc("DELETE /user/me", a.DeleteAccount, mw.Csrf)
+	me.HandleFunc("POST /user/me/restore", a.RestoreAccount, mw.Csrf)
+}
+
+// ExportData returns everything stored about the current user.
+// @ID exportUserData
+// @Summary ExportData
+// @Tags account
+// @Description download profile, reservations, reviews and notifications of the current user
+// @Produce application/zip
+// @Produce json
+// @Param format query string false "Archive format" Enums(zip, json)
+// @Success 200 {file} file
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/me/export [get]
+func (a *DefaultHandler) ExportData(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.account.handler.ExportData"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "zip" && format != "json" {
+		json.WriteError(w, r, "format must be zip or json", http.StatusBadRequest)
+		return
+	}
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	export, err := a.svc.Export(ctx, principal.UserId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	filename := fmt.Sprintf("inai-library-export-%s", export.GeneratedAt.Format("20060102-150405"))
+
+	if format == "json" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		json.WriteResponseBody(w, export, http.StatusOK)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeArchive(w, export); err != nil {
+		a.log.ErrorContext(ctx, "failed to write data export", slog.String("op", op), slog.String("error", err.Error()))
+	}
+}
+
+// writeArchive writes export as a zip with one json file per section.
+func writeArchive(w http.ResponseWriter, export accountDto.Export) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"reservations.json", export.Reservations},
+		{"reviews.json", export.Reviews},
+		{"notifications.json", export.Notifications},
+	}
+
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: export.GeneratedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		enc := stdJson.NewEncoder(fw)
+		enc.SetIndent("", "  ")
+
+		if err := enc.Encode(f.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// DeleteAccount schedules the current user's account for deletion.
+// @ID deleteAccount
+// @Summary DeleteAccount
+// @Tags account
+// @Description schedule deletion of the current user's account; reviews are kept anonymized
+// @Produce json
+// @Success 202 {object} httphelpers.Response
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/me [delete]
+func (a *DefaultHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.account.handler.DeleteAccount"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	deletion, err := a.svc.RequestDeletion(ctx, principal.UserId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "account scheduled for deletion", deletion, http.StatusAccepted)
+}
+
+// RestoreAccount cancels a scheduled deletion of the current user's account.
+// @ID restoreAccount
+// @Summary RestoreAccount
+// @Tags account
+// @Description cancel the scheduled deletion of the current user's account
+// @Produce json
+// @Success 200 {object} httphelpers.Response
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/me/restore [post]
+func (a *DefaultHandler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.account.handler.RestoreAccount"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	if err := a.svc.CancelDeletion(ctx, principal.UserId); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "account deletion cancelled", nil, http.StatusOK)
+}
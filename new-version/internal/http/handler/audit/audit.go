@@ -0,0 +1,242 @@
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"new-version/internal/config"
+	auditDto "new-version/internal/contract/audit"
+	"strconv"
+	"strings"
+	"time"
+
+	"new-version/internal/http/router"
+
+	auditSvc "new-version/internal/service/audit"
+
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+)
+
+const defaultPageSize = 50
+
+const invalidFilter = "from and to must be RFC 3339 timestamps or YYYY-MM-DD dates"
+
+// maxExportRows caps the CSV export, which isn't paginated.
+const maxExportRows = 10000
+
+type Handler interface {
+	ListEvents(w http.ResponseWriter, r *http.Request)
+	ExportEvents(w http.ResponseWriter, r *http.Request)
+}
+
+type DefaultHandler struct {
+	log     *slog.Logger
+	svc     auditSvc.Service
+	pageCfg *config.Pagination
+}
+
+func New(
+	log *slog.Logger,
+	svc auditSvc.Service,
+	pageCfg *config.Pagination,
+) *DefaultHandler {
+	return &DefaultHandler{
+		log:     log,
+		svc:     svc,
+		pageCfg: pageCfg,
+	}
+}
+
+// RegisterRoutes adds the routes to rt, protected by the shared
+// middlewares in mw.
+func (a *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	admin := rt.Group(mw.Auth.Require(hp.ADMIN_LVL), mw.RateLimit)
+	admin.HandleFunc("GET /audit/events", a.ListEvents)
+	admin.HandleFunc("GET /audit/events/export", a.ExportEvents)
+}
+
+// parseFilter reads actor, impersonator, action, target, outcome and the
+// from/to range (RFC 3339 timestamps or 2006-01-02 dates) from the query string.
+func parseFilter(q url.Values) (auditDto.Filter, error) {
+	filter := auditDto.Filter{
+		Actor:        q.Get("actor"),
+		Impersonator: q.Get("impersonator"),
+		Action:       q.Get("action"),
+		Target:       q.Get("target"),
+		Outcome:      q.Get("outcome"),
+	}
+
+	var err error
+
+	if filter.From, err = parseTime(q.Get("from")); err != nil {
+		return auditDto.Filter{}, fmt.Errorf("invalid from: %w", err)
+	}
+
+	if filter.To, err = parseTime(q.Get("to")); err != nil {
+		return auditDto.Filter{}, fmt.Errorf("invalid to: %w", err)
+	}
+
+	return filter, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// ListEvents returns a page of audit events.
+// @ID listAuditEvents
+// @Summary ListEvents
+// @Tags audit
+// @Description get audit events, newest first
+// @Produce json
+// @Param actor query string false "Actor"
+// @Param impersonator query string false "Impersonating admin"
+// @Param action query string false "Action"
+// @Param target query string false "Target"
+// @Param outcome query string false "Outcome" Enums(success, failure)
+// @Param from query string false "From (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "To, exclusive (RFC 3339 or YYYY-MM-DD)"
+// @Param page query int false "Page"
+// @Success 200 {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /audit/events [get]
+func (a *DefaultHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.audit.handler.ListEvents"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+
+	defer cancel()
+	defer r.Body.Close()
+
+	q := r.URL.Query()
+
+	filter, err := parseFilter(q)
+	if err != nil {
+		json.WriteBadRequest(w, r, invalidFilter, err)
+		return
+	}
+
+	page := 1
+	if p := q.Get("page"); p != "" {
+		page, err = strconv.Atoi(p)
+		if err != nil || page < 1 {
+			json.WriteError(w, r, "page must be a positive number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	filter.Limit = a.pageCfg.PageSize
+	if filter.Limit <= 0 {
+		filter.Limit = defaultPageSize
+	}
+	filter.Offset = (page - 1) * filter.Limit
+
+	events, err := a.svc.List(ctx, filter)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "fetched audit events", events, http.StatusOK)
+}
+
+// ExportEvents writes all matching audit events as CSV. Exports of more than
+// maxExportRows events are refused, the filter has to be narrowed instead.
+// @ID exportAuditEvents
+// @Summary ExportEvents
+// @Tags audit
+// @Description export audit events as csv
+// @Produce text/csv
+// @Param actor query string false "Actor"
+// @Param impersonator query string false "Impersonating admin"
+// @Param action query string false "Action"
+// @Param target query string false "Target"
+// @Param outcome query string false "Outcome" Enums(success, failure)
+// @Param from query string false "From (RFC 3339 or YYYY-MM-DD)"
+// @Param to query string false "To, exclusive (RFC 3339 or YYYY-MM-DD)"
+// @Success 200 {file} file
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /audit/events/export [get]
+func (a *DefaultHandler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.audit.handler.ExportEvents"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+
+	defer cancel()
+	defer r.Body.Close()
+
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		json.WriteBadRequest(w, r, invalidFilter, err)
+		return
+	}
+
+	// one more than allowed tells a full export from a truncated one
+	filter.Limit = maxExportRows + 1
+
+	events, err := a.svc.List(ctx, filter)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	if len(events) > maxExportRows {
+		json.WriteError(w, r, fmt.Sprintf("more than %d events match, narrow the filter", maxExportRows), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("audit-events-%s.csv", time.Now().UTC().Format("20060102-150405"))
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"id", "occurred_at", "actor", "impersonator", "action", "target", "ip", "user_agent", "outcome", "details"})
+
+	for _, e := range events {
+		cw.Write([]string{
+			strconv.FormatInt(e.Id, 10),
+			e.OccurredAt.UTC().Format(time.RFC3339),
+			csvCell(e.Actor),
+			csvCell(e.Impersonator),
+			csvCell(e.Action),
+			csvCell(e.Target),
+			csvCell(e.IP),
+			csvCell(e.UserAgent),
+			csvCell(e.Outcome),
+			csvCell(e.Details),
+		})
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		a.log.ErrorContext(ctx, "failed to write audit export", slog.String("op", op), slog.String("error", err.Error()))
+	}
+}
+
+// csvCell keeps spreadsheets from evaluating s as a formula. Most of the
+// audited values, like the user agent, are chosen by the client.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+
+	return s
+}
@@ -4,14 +4,13 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
-	"new-version/internal/config"
 	bookCatDto "new-version/internal/contract/bookcategory"
 
 	mwAuth "new-version/internal/http/middleware/auth"
-	mwChain "new-version/internal/http/middleware/chain"
-	mwLog "new-version/internal/http/middleware/logger"
+	mwCache "new-version/internal/http/middleware/cache"
+	"new-version/internal/http/router"
 
-	bookCatRepo "new-version/internal/repository/bookcategory"
+	bookCatSvc "new-version/internal/service/bookcategory"
 	"new-version/internal/validator/common"
 
 	hp "new-version/pkg/httphelpers"
@@ -29,34 +28,31 @@ type Handler interface {
 }
 
 type DefaultHandler struct {
-	log  *slog.Logger
-	repo bookCatRepo.Repository
-	cfg  *config.Security
+	log *slog.Logger
+	svc bookCatSvc.Service
 }
 
 func New(
 	log *slog.Logger,
-	repo bookCatRepo.Repository,
-	cfg *config.Security,
+	svc bookCatSvc.Service,
 ) *DefaultHandler {
 	return &DefaultHandler{
-		log:  log,
-		repo: repo,
-		cfg:  cfg,
+		log: log,
+		svc: svc,
 	}
 }
 
-func (b *DefaultHandler) RegisterRoutes(mux *http.ServeMux, log *slog.Logger, cfg *config.Security) {
-	ctx := context.Context(context.Background())
-	ctx = context.WithValue(ctx, "logger", log)
-	ctx = context.WithValue(ctx, "jwt_secret", cfg.JwtSecret)
-
-	mux.Handle("POST /book-category/", mwChain.Chain(ctx, b.CreateCategory, mwLog.Logger, mwAuth.Auth(hp.USER_LVL)))
-	mux.Handle("GET /book-category/{id}", mwChain.Chain(ctx, b.GetCategoryById, mwLog.Logger))
-	mux.Handle("PATCH /book-category/{id}", mwChain.Chain(ctx, b.UpdateCategoryById, mwLog.Logger, mwAuth.Auth(hp.ADMIN_LVL)))
-	mux.Handle("DELETE /book-category/{id}", mwChain.Chain(ctx, b.DeleteCategoryById, mwLog.Logger, mwAuth.Auth(hp.ADMIN_LVL)))
-	mux.Handle("GET /book-category/title", mwChain.Chain(ctx, b.GetCategoryByTitle, mwLog.Logger))
-	mux.Handle("GET /book-category/", mwChain.Chain(ctx, b.ListCategories, mwLog.Logger))
+// RegisterRoutes adds the routes to rt, protected by the shared
+// middlewares in mw.
+func (b *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	rt.HandleFunc("GET /book-category/{id}", b.GetCategoryById, mw.CatalogCache)
+	rt.HandleFunc("GET /book-category/title", b.GetCategoryByTitle, mw.CatalogCache)
+	rt.HandleFunc("GET /book-category/", b.ListCategories, mw.CatalogCache)
+	rt.HandleFunc("POST /book-category/", b.CreateCategory, mw.Auth.Require(hp.USER_LVL), mw.RateLimit, mw.Csrf)
+
+	admin := rt.Group(mw.Auth.Require(hp.ADMIN_LVL), mw.RateLimit, mw.Csrf)
+	admin.HandleFunc("PATCH /book-category/{id}", b.UpdateCategoryById)
+	admin.HandleFunc("DELETE /book-category/{id}", b.DeleteCategoryById)
 }
 
 // CreateCategory adds a new book category to library.
@@ -68,9 +64,11 @@ func (b *DefaultHandler) RegisterRoutes(mux *http.ServeMux, log *slog.Logger, cf
 // @Produce json
 // @Param req body bookcategory.Request true "CatRequest"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 422 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/ [post]
 func (b *DefaultHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.CreateCategory"
@@ -78,23 +76,25 @@ func (b *DefaultHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 
 	defer cancel()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
 	defer r.Body.Close()
 
 	var req bookCatDto.Request
 	if err := json.ReadRequestBody(r, &req); err != nil {
-
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid request body", err)
 		return
 	}
 
-	if common.IsFieldNotEmpty(req.Title) {
-		json.WriteError(w, common.FieldIsRequired(req.Title), http.StatusBadRequest)
+	if err := common.Validate(&req); err != nil {
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
-	id, err := b.repo.Create(ctx, req)
+	id, err := b.svc.Create(ctx, req)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
@@ -109,10 +109,14 @@ func (b *DefaultHandler) CreateCategory(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param id path int true "Category Id"
+// @Param If-None-Match header string false "ETag of the cached copy"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Success 304 "not modified"
+// @Header 200 {string} ETag "entity tag for If-None-Match"
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/{id} [get]
 func (b *DefaultHandler) GetCategoryById(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.GetCategoryById"
@@ -126,13 +130,17 @@ func (b *DefaultHandler) GetCategoryById(w http.ResponseWriter, r *http.Request)
 
 	if err != nil {
 		// id must be int
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid id", err)
 		return
 	}
 
-	bc, err := b.repo.GetById(ctx, id)
+	bc, err := b.svc.GetById(ctx, id)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	if mwCache.NotModified(w, r, etag(bc), bc.UpdatedTime) {
 		return
 	}
 
@@ -147,10 +155,14 @@ func (b *DefaultHandler) GetCategoryById(w http.ResponseWriter, r *http.Request)
 // @Accept json
 // @Produce json
 // @Param title query string true "Category Title"
+// @Param If-None-Match header string false "ETag of the cached copy"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Success 304 "not modified"
+// @Header 200 {string} ETag "entity tag for If-None-Match"
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/title [get]
 func (b *DefaultHandler) GetCategoryByTitle(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.GetCategoryByTitle"
@@ -162,14 +174,18 @@ func (b *DefaultHandler) GetCategoryByTitle(w http.ResponseWriter, r *http.Reque
 
 	title := r.URL.Query().Get("title")
 
-	if common.IsFieldNotEmpty(title) {
-		json.WriteError(w, common.FieldIsRequired(title), http.StatusBadRequest)
+	if !common.IsFieldNotEmpty(title) {
+		json.WriteError(w, r, common.FieldIsRequired("title"), http.StatusBadRequest)
 		return
 	}
 
-	bc, err := b.repo.GetByTitle(ctx, title)
+	bc, err := b.svc.GetByTitle(ctx, title)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	if mwCache.NotModified(w, r, etag(bc), bc.UpdatedTime) {
 		return
 	}
 
@@ -185,10 +201,17 @@ func (b *DefaultHandler) GetCategoryByTitle(w http.ResponseWriter, r *http.Reque
 // @Produce json
 // @Param input body bookcategory.Request true "CatRequest"
 // @Param id path int true "Category Id"
+// @Param If-Match header string true "ETag of the fetched category"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Header 200 {string} ETag "entity tag of the updated category"
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 412 {object} httphelpers.Problem
+// @Failure 422 {object} httphelpers.Problem
+// @Failure 428 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/{id} [patch]
 func (b *DefaultHandler) UpdateCategoryById(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.UpdateCategoryById"
@@ -196,6 +219,9 @@ func (b *DefaultHandler) UpdateCategoryById(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 
 	defer cancel()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
 	defer r.Body.Close()
 
 	id, err := hp.ParseIntIdFromPath(r)
@@ -203,22 +229,35 @@ func (b *DefaultHandler) UpdateCategoryById(w http.ResponseWriter, r *http.Reque
 	var req bookCatDto.Request
 
 	if err := json.ReadRequestBody(r, &req); err != nil {
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid request body", err)
 		return
 	}
 
 	if err != nil {
 		// id must be int
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid id", err)
+		return
+	}
+
+	if err := common.Validate(&req); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	current, ok := b.ifMatch(ctx, w, r, id)
+	if !ok {
 		return
 	}
 
-	err = b.repo.UpdateById(ctx, req, id)
+	err = b.svc.UpdateById(ctx, req, id, current.Version)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
+	current.Version++
+	w.Header().Set("ETag", etag(current))
+
 	json.WriteSuccess(w, "updated book category", map[string]any{"id": id}, http.StatusOK)
 }
 
@@ -230,10 +269,15 @@ func (b *DefaultHandler) UpdateCategoryById(w http.ResponseWriter, r *http.Reque
 // @Accept json
 // @Produce json
 // @Param id path int true "Category Id"
+// @Param If-Match header string true "ETag of the fetched category"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 412 {object} httphelpers.Problem
+// @Failure 428 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/{id} [delete]
 func (b *DefaultHandler) DeleteCategoryById(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.DeleteCategoryById"
@@ -241,19 +285,27 @@ func (b *DefaultHandler) DeleteCategoryById(w http.ResponseWriter, r *http.Reque
 	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 
 	defer cancel()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
 	defer r.Body.Close()
 
 	id, err := hp.ParseIntIdFromPath(r)
 
 	if err != nil {
 		// id must be int
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid id", err)
+		return
+	}
+
+	current, ok := b.ifMatch(ctx, w, r, id)
+	if !ok {
 		return
 	}
 
-	err = b.repo.DeleteById(ctx, id)
+	err = b.svc.DeleteById(ctx, id, current.Version)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
@@ -267,10 +319,13 @@ func (b *DefaultHandler) DeleteCategoryById(w http.ResponseWriter, r *http.Reque
 // @Description get list of book categories
 // @Accept json
 // @Produce json
+// @Param If-None-Match header string false "ETag of the cached copy"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Success 304 "not modified"
+// @Header 200 {string} ETag "entity tag for If-None-Match"
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /book-category/ [get]
 func (b *DefaultHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.bookcategory.handler.ListCategories"
@@ -280,11 +335,57 @@ func (b *DefaultHandler) ListCategories(w http.ResponseWriter, r *http.Request)
 	defer cancel()
 	defer r.Body.Close()
 
-	bcList, err := b.repo.GetList(ctx)
+	bcList, err := b.svc.GetList(ctx)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	// no Last-Modified, deleting a category doesn't change the newest update
+	if mwCache.NotModified(w, r, listETag(bcList), time.Time{}) {
 		return
 	}
 
 	json.WriteSuccess(w, "fetched book categories", bcList, http.StatusOK)
 }
+
+// ifMatch loads the category and checks that the If-Match header names its
+// current version, answering 428 without the header and 412 if the client
+// has an older version.
+func (b *DefaultHandler) ifMatch(ctx context.Context, w http.ResponseWriter, r *http.Request, id int) (bookCatDto.Response, bool) {
+	current, err := b.svc.GetById(ctx, id)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return bookCatDto.Response{}, false
+	}
+
+	present, match := mwCache.IfMatch(r, etag(current))
+	if !present {
+		json.WriteError(w, r, "If-Match with the ETag of the book category is required", http.StatusPreconditionRequired)
+		return bookCatDto.Response{}, false
+	}
+
+	if !match {
+		json.WriteError(w, r, "book category has been changed since it was fetched", http.StatusPreconditionFailed)
+		return bookCatDto.Response{}, false
+	}
+
+	return current, true
+}
+
+// representation tells the ETags of this API apart from the ones of /api/v1,
+// which shows the same rows differently.
+const representation = "v2"
+
+func etag(bc bookCatDto.Response) string {
+	return mwCache.ETag(representation, bc.Id, bc.Version)
+}
+
+func listETag(list []bookCatDto.Response) string {
+	parts := []any{representation}
+	for _, bc := range list {
+		parts = append(parts, bc.Id, bc.Version)
+	}
+
+	return mwCache.ETag(parts...)
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"log/slog"
+	"net/http"
+
+	healthDto "new-version/internal/contract/health"
+	"new-version/internal/http/router"
+
+	healthSvc "new-version/internal/service/health"
+
+	"new-version/pkg/json"
+)
+
+type Handler interface {
+	Liveness(w http.ResponseWriter, r *http.Request)
+	Readiness(w http.ResponseWriter, r *http.Request)
+	Version(w http.ResponseWriter, r *http.Request)
+}
+
+type DefaultHandler struct {
+	log *slog.Logger
+	svc healthSvc.Service
+}
+
+func New(log *slog.Logger, svc healthSvc.Service) *DefaultHandler {
+	return &DefaultHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+// RegisterRoutes adds the probe endpoints to rt. They're public so that the
+// orchestrator can reach them without credentials.
+func (h *DefaultHandler) RegisterRoutes(rt *router.Group) {
+	rt.HandleFunc("GET /healthz", h.Liveness)
+	rt.HandleFunc("GET /readyz", h.Readiness)
+	rt.HandleFunc("GET /version", h.Version)
+}
+
+// Liveness reports that the process serves requests.
+// @ID liveness
+// @Summary Liveness
+// @Tags health
+// @Description liveness probe, doesn't check dependencies
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func (h *DefaultHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	json.WriteResponseBody(w, healthDto.Report{Status: healthDto.StatusOK}, http.StatusOK)
+}
+
+// Readiness reports whether the server can handle traffic.
+// @ID readiness
+// @Summary Readiness
+// @Tags health
+// @Description readiness probe: database reachable, schema at the expected version, storage writable
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *DefaultHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.svc.Ready(r.Context())
+
+	code := http.StatusOK
+	if !report.OK() {
+		code = http.StatusServiceUnavailable
+	}
+
+	json.WriteResponseBody(w, report, code)
+}
+
+// Version returns the build and schema version.
+// @ID version
+// @Summary Version
+// @Tags health
+// @Description git commit, build time and schema version
+// @Produce json
+// @Success 200 {object} health.Version
+// @Router /version [get]
+func (h *DefaultHandler) Version(w http.ResponseWriter, r *http.Request) {
+	json.WriteResponseBody(w, h.svc.Version(r.Context()), http.StatusOK)
+}
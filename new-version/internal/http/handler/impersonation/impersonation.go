@@ -0,0 +1,141 @@
+package impersonation
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	mwAuth "new-version/internal/http/middleware/auth"
+	"new-version/internal/http/router"
+
+	impSvc "new-version/internal/service/impersonation"
+	"new-version/internal/validator/common"
+
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+
+	"github.com/google/uuid"
+)
+
+type Handler interface {
+	StartImpersonation(w http.ResponseWriter, r *http.Request)
+	EndImpersonation(w http.ResponseWriter, r *http.Request)
+}
+
+type DefaultHandler struct {
+	log *slog.Logger
+	svc impSvc.Service
+}
+
+type StartRequest struct {
+	UserId uuid.UUID `json:"user_id" validate:"required"`
+}
+
+func New(log *slog.Logger, svc impSvc.Service) *DefaultHandler {
+	return &DefaultHandler{
+		log: log,
+		svc: svc,
+	}
+}
+
+// RegisterRoutes adds the routes to rt, protected by the shared
+// middlewares in mw.
+func (i *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	rt.HandleFunc("POST /admin/impersonation", i.StartImpersonation, mw.Auth.Require(hp.ADMIN_LVL), mw.RateLimit, mw.Auth.NoImpersonation, mw.Csrf)
+	// called with the impersonation token, so it only needs the user level
+	rt.HandleFunc("DELETE /admin/impersonation", i.EndImpersonation, mw.Auth.Require(hp.USER_LVL), mw.RateLimit, mw.Csrf)
+}
+
+// StartImpersonation lets an admin act as a user.
+// @ID startImpersonation
+// @Summary StartImpersonation
+// @Tags admin
+// @Description issue a token acting as the user; send it as a Bearer token
+// @Accept json
+// @Produce json
+// @Param req body StartRequest true "User to impersonate"
+// @Success 201 {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 403 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 422 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /admin/impersonation [post]
+func (i *DefaultHandler) StartImpersonation(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.impersonation.handler.Start"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	var req StartRequest
+
+	if err := json.ReadRequestBody(r, &req); err != nil {
+		json.WriteBadRequest(w, r, "invalid request body", err)
+		return
+	}
+
+	if err := common.Validate(&req); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	started, err := i.svc.Start(ctx, impSvc.Admin{Id: principal.UserId, Email: principal.Email}, req.UserId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "impersonation started", started, http.StatusCreated)
+}
+
+// EndImpersonation revokes the impersonation token the request is made with.
+// @ID endImpersonation
+// @Summary EndImpersonation
+// @Tags admin
+// @Description end the impersonation session of the token
+// @Produce json
+// @Success 200 {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /admin/impersonation [delete]
+func (i *DefaultHandler) EndImpersonation(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.impersonation.handler.End"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	if !principal.Impersonated() {
+		json.WriteError(w, r, "token is not an impersonation token", http.StatusBadRequest)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	if err := i.svc.End(ctx, principal.UserId, principal.SessionId); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "impersonation ended", nil, http.StatusOK)
+}
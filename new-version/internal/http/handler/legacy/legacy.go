@@ -0,0 +1,470 @@
+package legacy
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"new-version/internal/config"
+	bookCatDto "new-version/internal/contract/bookcategory"
+	legacyDto "new-version/internal/contract/legacy"
+	userDto "new-version/internal/contract/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCache "new-version/internal/http/middleware/cache"
+	"new-version/internal/http/router"
+
+	auditSvc "new-version/internal/service/audit"
+	bookCatSvc "new-version/internal/service/bookcategory"
+	sessionSvc "new-version/internal/service/session"
+	userSvc "new-version/internal/service/user"
+	"new-version/internal/validator/common"
+	"new-version/pkg/errs"
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+	"strconv"
+	"time"
+)
+
+// notPorted are the apps of the Django API without a Go service yet. Their
+// routes answer 501 in the legacy shape instead of a bare 404.
+var notPorted = []string{"user", "group", "activate", "subcategory", "book", "ebook", "order", "review", "message"}
+
+// representation tells the ETags of this API apart from the ones of
+// /api/v2, which shows the same rows differently.
+const representation = "v1"
+
+type Handler interface {
+	Register(w http.ResponseWriter, r *http.Request)
+	Login(w http.ResponseWriter, r *http.Request)
+	Logout(w http.ResponseWriter, r *http.Request)
+	ListCategories(w http.ResponseWriter, r *http.Request)
+	CreateCategory(w http.ResponseWriter, r *http.Request)
+	GetCategory(w http.ResponseWriter, r *http.Request)
+	UpdateCategory(w http.ResponseWriter, r *http.Request)
+	DeleteCategory(w http.ResponseWriter, r *http.Request)
+	NotPorted(w http.ResponseWriter, r *http.Request)
+}
+
+// DefaultHandler serves the paths and response shapes of the Django API on
+// top of the Go services, so the old front-end keeps working until it moves
+// to /api/v2. Errors of the shared middlewares, like a missing token, keep
+// the problem format.
+type DefaultHandler struct {
+	log        *slog.Logger
+	users      userSvc.Service
+	sessions   sessionSvc.Service
+	categories bookCatSvc.Service
+	cfg        *config.Security
+}
+
+func New(
+	log *slog.Logger,
+	users userSvc.Service,
+	sessions sessionSvc.Service,
+	categories bookCatSvc.Service,
+	cfg *config.Security,
+) *DefaultHandler {
+	return &DefaultHandler{
+		log:        log,
+		users:      users,
+		sessions:   sessions,
+		categories: categories,
+		cfg:        cfg,
+	}
+}
+
+// RegisterRoutes adds the routes to rt, which is expected to be under
+// /api/v1, protected by the shared middlewares in mw. Authorization follows
+// /api/v2 rather than the Django permissions.
+func (l *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	credentials := rt.Group(mw.AuthRateLimit)
+	credentials.HandleFunc("POST /register", l.Register)
+	credentials.HandleFunc("POST /login", l.Login)
+	rt.HandleFunc("POST /logout", l.Logout)
+
+	rt.HandleFunc("GET /category/all", l.ListCategories, mw.CatalogCache)
+	rt.HandleFunc("GET /category/{id}", l.GetCategory, mw.CatalogCache)
+	rt.HandleFunc("POST /category/create", l.CreateCategory, mw.Auth.Require(hp.USER_LVL), mw.RateLimit, mw.Csrf)
+
+	admin := rt.Group(mw.Auth.Require(hp.ADMIN_LVL), mw.RateLimit, mw.Csrf)
+	admin.HandleFunc("PUT /category/{id}", l.UpdateCategory)
+	admin.HandleFunc("PATCH /category/{id}", l.UpdateCategory)
+	admin.HandleFunc("DELETE /category/{id}", l.DeleteCategory)
+
+	for _, app := range notPorted {
+		rt.HandleFunc("/"+app+"/", l.NotPorted)
+	}
+}
+
+// Register signs the user up and in, as the Django view did.
+func (l *DefaultHandler) Register(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.Register"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	ctx = auditSvc.WithRequest(ctx, r, "")
+
+	var req legacyDto.Credentials
+	if !l.readRequest(w, r, &req) {
+		return
+	}
+
+	user := userDto.Request{Email: req.Email, Password: req.Password}
+
+	if err := l.users.Register(ctx, user); err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	token, err := l.users.Login(ctx, user)
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	l.writeAuth(w, r, "Пользователь успешно зарегистрирован", token, http.StatusCreated)
+}
+
+func (l *DefaultHandler) Login(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.Login"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	ctx = auditSvc.WithRequest(ctx, r, "")
+
+	var req legacyDto.Credentials
+	if !l.readRequest(w, r, &req) {
+		return
+	}
+
+	token, err := l.users.Login(ctx, userDto.Request{Email: req.Email, Password: req.Password})
+	if errors.Is(err, errs.ErrUnauthorized) {
+		writeMessage(w, "Неверный email или пароль", http.StatusBadRequest)
+		return
+	}
+
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	l.writeAuth(w, r, "Пользователь успешно вошел в систему", token, http.StatusOK)
+}
+
+// Logout revokes the session of the token sent as refresh_token.
+func (l *DefaultHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.Logout"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	var req legacyDto.LogoutRequest
+	if err := json.ReadRequestBody(r, &req); err != nil || req.RefreshToken == "" {
+		writeMessage(w, "Отсутствует Refresh токен", http.StatusBadRequest)
+		return
+	}
+
+	principal, err := mwAuth.PrincipalFromToken(req.RefreshToken, l.cfg.JwtSecret)
+	if err != nil {
+		writeMessage(w, "Неверный токен или токен просрочен.", http.StatusBadRequest)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	if err := l.sessions.Revoke(ctx, principal.UserId, principal.SessionId); err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			writeMessage(w, "Неверный токен или токен просрочен.", http.StatusBadRequest)
+			return
+		}
+
+		l.writeError(w, r, err)
+		return
+	}
+
+	writeMessage(w, "Пользователь успешно вышел из системы.", http.StatusOK)
+}
+
+func (l *DefaultHandler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.ListCategories"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	list, err := l.categories.GetList(ctx)
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	parts := []any{representation}
+	categories := make([]legacyDto.Category, 0, len(list))
+	for _, c := range list {
+		parts = append(parts, c.Id, c.Version)
+		categories = append(categories, toCategory(c))
+	}
+
+	if mwCache.NotModified(w, r, mwCache.ETag(parts...), time.Time{}) {
+		return
+	}
+
+	json.WriteResponseBody(w, categories, http.StatusOK)
+}
+
+func (l *DefaultHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.CreateCategory"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	var req legacyDto.CategoryRequest
+	if !l.readRequest(w, r, &req) {
+		return
+	}
+
+	id, err := l.categories.Create(ctx, bookCatDto.Request{Title: req.Title})
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	// new rows start at version 1
+	json.WriteResponseBody(w, legacyDto.Category{Id: id, Title: req.Title, Version: 1}, http.StatusCreated)
+}
+
+func (l *DefaultHandler) GetCategory(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.GetCategory"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	id, ok := categoryId(w, r)
+	if !ok {
+		return
+	}
+
+	c, err := l.categories.GetById(ctx, id)
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	if mwCache.NotModified(w, r, etag(c), c.UpdatedTime) {
+		return
+	}
+
+	json.WriteResponseBody(w, toCategory(c), http.StatusOK)
+}
+
+func (l *DefaultHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.UpdateCategory"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	id, ok := categoryId(w, r)
+	if !ok {
+		return
+	}
+
+	var req legacyDto.CategoryRequest
+	if !l.readRequest(w, r, &req) {
+		return
+	}
+
+	version, ok := l.version(ctx, w, r, id, req.Version)
+	if !ok {
+		return
+	}
+
+	if err := l.categories.UpdateById(ctx, bookCatDto.Request{Title: req.Title}, id, version); err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	updated := bookCatDto.Response{Id: id, Title: req.Title, Version: version + 1}
+	w.Header().Set("ETag", etag(updated))
+
+	json.WriteResponseBody(w, toCategory(updated), http.StatusOK)
+}
+
+func (l *DefaultHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.legacy.handler.DeleteCategory"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, _ := mwAuth.PrincipalFromContext(r.Context())
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	id, ok := categoryId(w, r)
+	if !ok {
+		return
+	}
+
+	var sent int
+	if v := r.URL.Query().Get("version"); v != "" {
+		var err error
+		if sent, err = strconv.Atoi(v); err != nil {
+			writeMessage(w, "Неправильный запрос.", http.StatusBadRequest)
+			return
+		}
+	}
+
+	version, ok := l.version(ctx, w, r, id, sent)
+	if !ok {
+		return
+	}
+
+	if err := l.categories.DeleteById(ctx, id, version); err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// version returns the version of the category the client read, named by
+// If-Match or, for the old front-end that can't set headers, by the version
+// it got with the category: sent back in the body of PUT and PATCH or as the
+// version query parameter of DELETE. Writes naming neither are refused, so
+// that two librarians can't silently overwrite each other's edits. A stale
+// sent version is detected by the write itself.
+func (l *DefaultHandler) version(ctx context.Context, w http.ResponseWriter, r *http.Request, id int, sent int) (int, bool) {
+	if !mwCache.HasIfMatch(r) {
+		if sent < 1 {
+			writeMessage(w, "Не указана версия записи, обновите страницу", http.StatusPreconditionRequired)
+			return 0, false
+		}
+
+		return sent, true
+	}
+
+	current, err := l.categories.GetById(ctx, id)
+	if err != nil {
+		l.writeError(w, r, err)
+		return 0, false
+	}
+
+	if _, match := mwCache.IfMatch(r, etag(current)); !match {
+		writeMessage(w, "Запись была изменена, обновите страницу", http.StatusPreconditionFailed)
+		return 0, false
+	}
+
+	return current.Version, true
+}
+
+func (l *DefaultHandler) NotPorted(w http.ResponseWriter, r *http.Request) {
+	writeMessage(w, "Раздел пока недоступен", http.StatusNotImplemented)
+}
+
+func etag(c bookCatDto.Response) string {
+	return mwCache.ETag(representation, c.Id, c.Version)
+}
+
+func toCategory(c bookCatDto.Response) legacyDto.Category {
+	return legacyDto.Category{Id: c.Id, Title: c.Title, Version: c.Version}
+}
+
+func categoryId(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := hp.ParseIntIdFromPath(r)
+	if err != nil {
+		// Django only matched integer ids
+		writeMessage(w, "Не найдено.", http.StatusNotFound)
+		return 0, false
+	}
+
+	return id, true
+}
+
+// readRequest decodes and validates the body into req, answering 400 like
+// DRF serializers did if it can't. Decoder errors are logged, not shown.
+func (l *DefaultHandler) readRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.ReadRequestBody(r, req); err != nil {
+		l.log.InfoContext(r.Context(), "bad request",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+		)
+
+		writeMessage(w, "Неправильный запрос.", http.StatusBadRequest)
+		return false
+	}
+
+	if err := common.Validate(req); err != nil {
+		l.writeError(w, r, err)
+		return false
+	}
+
+	return true
+}
+
+func (l *DefaultHandler) writeAuth(w http.ResponseWriter, r *http.Request, msg string, token string, code int) {
+	principal, err := mwAuth.PrincipalFromToken(token, l.cfg.JwtSecret)
+	if err != nil {
+		l.writeError(w, r, err)
+		return
+	}
+
+	json.WriteResponseBody(w, legacyDto.AuthResponse{
+		Message:      msg,
+		AccessToken:  token,
+		RefreshToken: token,
+		User: legacyDto.User{
+			Id:    principal.UserId,
+			Email: principal.Email,
+			Role:  role(principal.AccessLevel),
+		},
+	}, code)
+}
+
+// role maps access levels to the Django roles. There are no librarians
+// anymore, staff are admins.
+func role(level hp.AccessLevel) string {
+	if level >= hp.ADMIN_LVL {
+		return "Admin"
+	}
+
+	return "Student"
+}
+
+// writeError writes err in the legacy shape. Validation errors are 400 as
+// in DRF, internal errors are logged and not shown.
+func (l *DefaultHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	msg, ok := errs.Message(err)
+	if !ok {
+		l.log.ErrorContext(r.Context(), "internal server error",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+		)
+
+		writeMessage(w, "Внутренняя ошибка сервера", http.StatusInternalServerError)
+		return
+	}
+
+	code := hp.StatusFromError(err)
+	if code == http.StatusUnprocessableEntity {
+		code = http.StatusBadRequest
+	}
+
+	writeMessage(w, msg, code)
+}
+
+func writeMessage(w http.ResponseWriter, msg string, code int) {
+	json.WriteResponseBody(w, legacyDto.Message{Message: msg}, code)
+}
@@ -2,53 +2,71 @@ package user
 
 import (
 	"context"
-	"log"
 	"log/slog"
 	"net/http"
 	"new-version/internal/config"
 	"new-version/internal/contract/user"
 	userDto "new-version/internal/contract/user"
+	"new-version/internal/http/cookie"
 	mwAuth "new-version/internal/http/middleware/auth"
-	mwChain "new-version/internal/http/middleware/chain"
-	mwLog "new-version/internal/http/middleware/logger"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+	"new-version/internal/http/router"
 
+	auditSvc "new-version/internal/service/audit"
+	sessionSvc "new-version/internal/service/session"
 	userSvc "new-version/internal/service/user"
+	"new-version/internal/validator/common"
 	hp "new-version/pkg/httphelpers"
 	"new-version/pkg/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Handler interface {
 	RegisterUser(w http.ResponseWriter, r *http.Request)
 	LoginUser(w http.ResponseWriter, r *http.Request)
 	LogoutUser(w http.ResponseWriter, r *http.Request)
+	CsrfToken(w http.ResponseWriter, r *http.Request)
+	ListSessions(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
 }
 
 type DefaultHandler struct {
-	log *slog.Logger
-	svc userSvc.Service
-	cfg *config.Security
+	log      *slog.Logger
+	svc      userSvc.Service
+	sessions sessionSvc.Service
+	cfg      *config.Security
+	cookies  *cookie.Jar
 }
 
-func (u *DefaultHandler) RegisterRoutes(mux *http.ServeMux, log *slog.Logger, cfg *config.Security) {
-	ctx := context.Context(context.Background())
-	ctx = context.WithValue(ctx, "logger", log)
-	ctx = context.WithValue(ctx, "jwt_secret", cfg.JwtSecret)
+// RegisterRoutes adds the routes to rt, protected by the shared
+// middlewares in mw.
+func (u *DefaultHandler) RegisterRoutes(rt *router.Group, mw *router.Middlewares) {
+	credentials := rt.Group(mw.AuthRateLimit)
+	credentials.HandleFunc("POST /user/register", u.RegisterUser)
+	credentials.HandleFunc("POST /user/login", u.LoginUser)
 
-	mux.Handle("POST /user/register", mwChain.Chain(ctx, u.RegisterUser, mwLog.Logger))
-	mux.Handle("POST /user/login", mwChain.Chain(ctx, u.LoginUser, mwLog.Logger))
-	mux.Handle("POST /user/logout", mwChain.Chain(ctx, u.LoginUser, mwLog.Logger, mwAuth.Auth(hp.USER_LVL)))
+	authed := rt.Group(mw.Auth.Require(hp.USER_LVL), mw.RateLimit)
+	authed.HandleFunc("GET /user/csrf-token", u.CsrfToken)
+	authed.HandleFunc("POST /user/logout", u.LogoutUser, mw.Csrf)
+	authed.HandleFunc("GET /user/sessions", u.ListSessions)
+	authed.HandleFunc("DELETE /user/sessions/{id}", u.RevokeSession, mw.Auth.NoImpersonation, mw.Csrf)
 }
 
 func New(
 	log *slog.Logger,
 	srv userSvc.Service,
+	sessions sessionSvc.Service,
 	cfg *config.Security,
+	cookies *cookie.Jar,
 ) *DefaultHandler {
 	return &DefaultHandler{
-		log: log,
-		svc: srv,
-		cfg: cfg,
+		log:      log,
+		svc:      srv,
+		sessions: sessions,
+		cfg:      cfg,
+		cookies:  cookies,
 	}
 }
 
@@ -61,27 +79,36 @@ func New(
 // @Produce json
 // @Param req body user.Request true "UserCreate"
 // @Success 201 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 409 {object} httphelpers.Problem
+// @Failure 422 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /user/register [post]
 func (u *DefaultHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.user.handler.Register"
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*3))
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 	defer r.Body.Close()
 
+	ctx = auditSvc.WithRequest(ctx, r, "")
+
 	var req userDto.Request
 
 	if err := json.ReadRequestBody(r, &req); err != nil {
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid request body", err)
+		return
+	}
+
+	if err := common.Validate(&req); err != nil {
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
 	err := u.svc.Register(ctx, req)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
@@ -97,77 +124,203 @@ func (u *DefaultHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param req body user.Request true "UserLogin"
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 422 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /user/login [post]
 func (u *DefaultHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.user.handler.Login"
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(time.Second*3))
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
 	defer cancel()
 	defer r.Body.Close()
 
+	ctx = auditSvc.WithRequest(ctx, r, "")
+
 	var req user.Request
 
 	if err := json.ReadRequestBody(r, &req); err != nil {
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+		json.WriteBadRequest(w, r, "invalid request body", err)
+		return
+	}
+
+	if err := common.Validate(&req); err != nil {
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
 	token, err := u.svc.Login(ctx, req)
 	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusInternalServerError)
+		json.WriteErrorFrom(w, r, err)
 		return
 	}
 
-	log.Println(token)
+	principal, err := mwAuth.PrincipalFromToken(token, u.cfg.JwtSecret)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "access_token",
-		Value:    token,
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(u.cfg.AccessTokenExpire),
-	})
+	csrfToken, err := mwCsrf.NewToken(u.cfg.JwtSecret, principal.SessionId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
 
-	json.WriteSuccess(w, "successful login", nil, http.StatusOK)
+	u.cookies.Set(w, "access_token", token, u.cfg.AccessTokenExpire)
+	mwCsrf.SetCookie(w, u.cookies, csrfToken)
+
+	json.WriteSuccess(w, "successful login", map[string]any{"csrf_token": csrfToken}, http.StatusOK)
 }
 
 // Logout allows a user to sign out from system and to be protected.
+// It revokes the session of the access token, whether sent as bearer token
+// or cookie.
 // @ID logoutUser
 // @Summary Logout
 // @Tags user
 // @Description logout user
 // @Produce json
 // @Success 200 {object} httphelpers.Response
-// @Failure 400 {object} httphelpers.Response
-// @Failure 500 {object} httphelpers.Response
-// @Failure default {object} httphelpers.Response
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
 // @Router /user/logout [post]
 func (u *DefaultHandler) LogoutUser(w http.ResponseWriter, r *http.Request) {
 	const op = "modules.user.handler.Logout"
 
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
 	defer r.Body.Close()
 
-	cookie, err := r.Cookie("access_token")
-	if err != nil {
-		json.WriteError(w, err.Error(), http.StatusBadRequest)
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
 		return
 	}
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     cookie.Name,
-		Value:    "",
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   false,
-		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-	})
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	if err := u.sessions.Revoke(ctx, principal.UserId, principal.SessionId); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	// clearing them is harmless for clients sending a bearer token
+	u.cookies.Clear(w, "access_token")
+	mwCsrf.ClearCookie(w, u.cookies)
 
 	json.WriteSuccess(w, "successful logout", nil, http.StatusOK)
 }
+
+// CsrfToken issues a token for cookie-authenticated requests of the current
+// session.
+// @ID csrfToken
+// @Summary CsrfToken
+// @Tags user
+// @Description issue a csrf token; send it back in the X-CSRF-Token header of POST, PUT, PATCH and DELETE requests
+// @Produce json
+// @Success 200 {object} httphelpers.Response
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/csrf-token [get]
+func (u *DefaultHandler) CsrfToken(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.user.handler.CsrfToken"
+
+	defer r.Body.Close()
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := mwCsrf.NewToken(u.cfg.JwtSecret, principal.SessionId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	mwCsrf.SetCookie(w, u.cookies, token)
+
+	json.WriteSuccess(w, "issued csrf token", map[string]any{"csrf_token": token}, http.StatusOK)
+}
+
+// ListSessions shows where the user is logged in.
+// @ID listUserSessions
+// @Summary ListSessions
+// @Tags user
+// @Description list active sessions of the current user
+// @Produce json
+// @Success 200 {object} httphelpers.Response
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/sessions [get]
+func (u *DefaultHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.user.handler.ListSessions"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := u.sessions.List(ctx, principal.UserId, principal.SessionId)
+	if err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "fetched sessions", sessions, http.StatusOK)
+}
+
+// RevokeSession logs the user out on another device.
+// @ID revokeUserSession
+// @Summary RevokeSession
+// @Tags user
+// @Description revoke a session of the current user
+// @Produce json
+// @Param id path string true "Session Id"
+// @Success 200 {object} httphelpers.Response
+// @Failure 400 {object} httphelpers.Problem
+// @Failure 401 {object} httphelpers.Problem
+// @Failure 404 {object} httphelpers.Problem
+// @Failure 500 {object} httphelpers.Problem
+// @Failure default {object} httphelpers.Problem
+// @Router /user/sessions/{id} [delete]
+func (u *DefaultHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	const op = "modules.user.handler.RevokeSession"
+
+	ctx, cancel := context.WithTimeout(r.Context(), 3*time.Second)
+	defer cancel()
+	defer r.Body.Close()
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		json.WriteBadRequest(w, r, "invalid session id", err)
+		return
+	}
+
+	principal, ok := mwAuth.PrincipalFromContext(r.Context())
+	if !ok {
+		json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx = mwAuth.WithAudit(ctx, r, principal)
+
+	if err := u.sessions.Revoke(ctx, principal.UserId, id); err != nil {
+		json.WriteErrorFrom(w, r, err)
+		return
+	}
+
+	json.WriteSuccess(w, "revoked session", map[string]any{"id": id}, http.StatusOK)
+}
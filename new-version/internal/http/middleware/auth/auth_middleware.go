@@ -2,77 +2,203 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
-	"new-version/internal/config"
+	mwLog "new-version/internal/http/middleware/logger"
+	auditSvc "new-version/internal/service/audit"
 	"new-version/internal/validator/user"
+	"new-version/pkg/errs"
 	"new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+	"new-version/pkg/logger"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
-func AuthMiddleware(cfg *config.Security) func(next http.Handler, level httphelpers.AccessLevel) http.Handler {
-	return func(next http.Handler, level httphelpers.AccessLevel) http.Handler {
+type SessionValidator interface {
+	Validate(ctx context.Context, id uuid.UUID) error
+}
+
+// Principal is the authenticated caller described by the access token.
+type Principal struct {
+	UserId      uuid.UUID
+	SessionId   uuid.UUID
+	Email       string
+	AccessLevel httphelpers.AccessLevel
+
+	// ImpersonatorId and Impersonator identify the admin acting as the
+	// user. They're empty for the user's own tokens.
+	ImpersonatorId uuid.UUID
+	Impersonator   string
+}
+
+func (p Principal) Impersonated() bool {
+	return p.Impersonator != ""
+}
+
+// TokenFromRequest returns the access token sent as "Authorization: Bearer"
+// header or, failing that, as the access_token cookie. fromCookie reports
+// whether the cookie was used.
+func TokenFromRequest(r *http.Request) (token string, fromCookie bool) {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token), false
+		}
+	}
+
+	tc, err := r.Cookie("access_token")
+	if err != nil {
+		return "", false
+	}
+
+	return tc.Value, true
+}
+
+func PrincipalFromRequest(r *http.Request, jwtSecret string) (Principal, error) {
+	token, _ := TokenFromRequest(r)
+
+	return PrincipalFromToken(token, jwtSecret)
+}
+
+// PrincipalFromToken is PrincipalFromRequest for a token sent some other way.
+// It doesn't check whether the session is still active.
+func PrincipalFromToken(token string, jwtSecret string) (Principal, error) {
+	if token == "" {
+		return Principal{}, errors.New("missing or empty token")
+	}
+
+	claims, err := user.ValidateJwt(jwtSecret, token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	var p Principal
+
+	p.Email, _ = claims["sub"].(string)
+
+	if lvl, ok := claims["user_level"].(float64); ok {
+		p.AccessLevel = httphelpers.AccessLevel(lvl)
+	}
+
+	if uid, ok := claims["uid"].(string); ok {
+		p.UserId, _ = uuid.Parse(uid)
+	}
+
+	if sid, ok := claims["sid"].(string); ok {
+		p.SessionId, _ = uuid.Parse(sid)
+	}
+
+	p.Impersonator, _ = claims["imp_sub"].(string)
+
+	if impUid, ok := claims["imp_uid"].(string); ok {
+		p.ImpersonatorId, _ = uuid.Parse(impUid)
+	}
+
+	return p, nil
+}
+
+// WithAudit puts the audit meta of the request made by p into ctx, so that
+// impersonated actions are recorded with the admin behind them.
+func WithAudit(ctx context.Context, r *http.Request, p Principal) context.Context {
+	return auditSvc.WithMeta(ctx, auditSvc.Meta{
+		Actor:        p.Email,
+		Impersonator: p.Impersonator,
+		IP:           httphelpers.ClientIP(r),
+		UserAgent:    r.UserAgent(),
+	})
+}
+
+type principalKey struct{}
+
+// WithPrincipal stores p in ctx. Auth does it for every authenticated request.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller authenticated by Auth.Require.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Auth authenticates requests with access tokens signed with secret whose
+// sessions are still active.
+type Auth struct {
+	secret   string
+	sessions SessionValidator
+}
+
+func New(secret string, sessions SessionValidator) *Auth {
+	return &Auth{secret: secret, sessions: sessions}
+}
+
+// Require rejects requests without a valid token of at least level and puts
+// the principal into the request context.
+func (a *Auth) Require(level httphelpers.AccessLevel) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			tc, err := r.Cookie("access_token")
-			if err != nil || tc.Value == "" {
-				http.Error(w, "missing or empty token", http.StatusUnauthorized)
+			token, _ := TokenFromRequest(r)
+			if token == "" {
+				json.WriteError(w, r, "missing or empty token", http.StatusUnauthorized)
 				return
 			}
 
-			claims, err := user.ValidateJwt(cfg.JwtSecret, tc.Value)
+			p, err := PrincipalFromRequest(r, a.secret)
 			if err != nil {
-				http.Error(w, "invalid token", http.StatusUnauthorized)
+				json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			lvlFloat, ok := claims["access_level"].(float64)
-			if !ok {
-				http.Error(w, "invalid access level", http.StatusUnauthorized)
+			if p.AccessLevel < level {
+				json.WriteError(w, r, "no permission for action", http.StatusForbidden)
 				return
 			}
 
-			lvl := httphelpers.AccessLevel(int(lvlFloat))
+			if p.SessionId == uuid.Nil {
+				json.WriteError(w, r, "invalid session", http.StatusUnauthorized)
+				return
+			}
 
-			if lvl < level {
-				http.Error(w, "no permission for action", http.StatusForbidden)
+			if err := a.sessions.Validate(r.Context(), p.SessionId); err != nil {
+				// a failing database mustn't log everyone out
+				if errors.Is(err, errs.ErrUnauthorized) || errors.Is(err, errs.ErrNotFound) {
+					json.WriteError(w, r, "session is revoked or expired", http.StatusUnauthorized)
+					return
+				}
+
+				json.WriteErrorFrom(w, r, err)
 				return
 			}
 
-			next.ServeHTTP(w, r)
+			mwLog.SetUser(r.Context(), p.UserId.String())
+
+			ctx := WithPrincipal(r.Context(), p)
+			ctx = logger.WithAttrs(ctx, slog.String(logger.KeyUserID, p.UserId.String()))
+
+			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-func Auth(handlerLevel httphelpers.AccessLevel) func(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
-	return func(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
-		jwtSecret, ok := ctx.Value("jwt_secret").(string)
-		if !ok || jwtSecret == "" {
-			http.Error(w, "internal server error", http.StatusInternalServerError)
-			return false
-		}
-
-		tc, err := r.Cookie("access_token")
-		if err != nil || tc.Value == "" {
-			http.Error(w, "missing or empty token", http.StatusUnauthorized)
-			return false
-		}
-
-		claims, err := user.ValidateJwt(jwtSecret, tc.Value)
-		if err != nil {
-			http.Error(w, "invalid token", http.StatusUnauthorized)
-			return false
-		}
-
-		res, ok := claims["user_level"].(float64)
+// NoImpersonation rejects requests made with an impersonation token. It's
+// meant for sensitive actions like deleting the account, and must follow
+// Require.
+func (a *Auth) NoImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := PrincipalFromContext(r.Context())
 		if !ok {
-			http.Error(w, "invalid access level", http.StatusUnauthorized)
-			return false
+			json.WriteError(w, r, "invalid token", http.StatusUnauthorized)
+			return
 		}
-		userLevel := httphelpers.AccessLevel(res)
 
-		if userLevel < handlerLevel {
-			http.Error(w, "no permission for action", http.StatusForbidden)
-			return false
+		if p.Impersonated() {
+			json.WriteError(w, r, "not allowed while impersonating a user", http.StatusForbidden)
+			return
 		}
 
-		return true
-	}
+		next.ServeHTTP(w, r)
+	})
 }
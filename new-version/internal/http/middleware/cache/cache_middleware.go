@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	mwChain "new-version/internal/http/middleware/chain"
+)
+
+// Public lets browsers and shared proxies reuse a response for maxAge. With
+// a maxAge of 0 they have to revalidate it on every use.
+func Public(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "public, no-cache"
+	}
+
+	return fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+}
+
+// Control sets policy as Cache-Control of successful and not modified
+// responses. Other responses get no-store, so errors aren't cached.
+func Control(policy string) mwChain.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&controlWriter{ResponseWriter: w, policy: policy}, r)
+		})
+	}
+}
+
+type controlWriter struct {
+	http.ResponseWriter
+	policy      string
+	wroteHeader bool
+}
+
+func (c *controlWriter) WriteHeader(code int) {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+
+		if code == http.StatusOK || code == http.StatusNotModified {
+			c.Header().Set("Cache-Control", c.policy)
+		} else {
+			c.Header().Set("Cache-Control", "no-store")
+		}
+	}
+
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *controlWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *controlWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// ETag returns a strong entity tag of the parts, like a representation name
+// followed by the ids and update times of the rows it shows.
+func ETag(parts ...any) string {
+	h := sha256.New()
+
+	for _, p := range parts {
+		// the monotonic reading and location of times don't make a new version
+		if t, ok := p.(time.Time); ok {
+			p = t.UnixNano()
+		}
+
+		fmt.Fprint(h, p)
+		h.Write([]byte{0})
+	}
+
+	return `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// NotModified sets the ETag and, unless modified is zero, the Last-Modified
+// header of the response. For GET and HEAD it then evaluates If-None-Match
+// or, without it, If-Modified-Since, and writes 304 if the client's copy is
+// current. The handler must not write a body when it returns true.
+func NotModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	h := w.Header()
+	h.Set("ETag", etag)
+
+	if !modified.IsZero() {
+		h.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := header(r, "If-None-Match"); inm != "" {
+		if !weakMatch(inm, etag) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		// HTTP dates have no fraction of a second
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(ims) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// IfMatch reports whether the If-Match header of r names an entity tag and
+// whether one of them is etag. Unlike If-None-Match it uses the strong
+// comparison, so weak tags never match. A * names no version and can't
+// guard a write against a concurrent one, so it's ignored.
+func IfMatch(r *http.Request, etag string) (present bool, match bool) {
+	for _, tag := range strings.Split(header(r, "If-Match"), ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		present = true
+		if tag == etag {
+			return true, true
+		}
+	}
+
+	return present, false
+}
+
+// HasIfMatch reports whether the If-Match header of r names an entity tag.
+func HasIfMatch(r *http.Request) bool {
+	present, _ := IfMatch(r, "")
+	return present
+}
+
+// weakMatch compares etag to the tags of an If-None-Match header the weak
+// way RFC 9110 asks for.
+func weakMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// header joins the lines of a list header.
+func header(r *http.Request, name string) string {
+	return strings.Join(r.Header.Values(name), ",")
+}
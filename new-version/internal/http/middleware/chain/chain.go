@@ -1,25 +1,17 @@
 package chain
 
 import (
-	"context"
 	"net/http"
 )
 
-type Middleware func(ctx context.Context, w http.ResponseWriter, r *http.Request) bool
+type Middleware func(http.Handler) http.Handler
 
-func Chain(
-	ctx context.Context,
-	handler func(w http.ResponseWriter, r *http.Request),
-	middlewares ...Middleware,
-) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		for _, mw := range middlewares {
-			ok := mw(ctx, w, r)
-			if !ok {
-				return
-			}
-		}
+// Chain wraps handler in middlewares. The first middleware is the outermost,
+// so it sees the request first and the response last.
+func Chain(handler http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
 
-		handler(w, r)
-	})
+	return handler
 }
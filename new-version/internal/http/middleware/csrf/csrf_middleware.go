@@ -0,0 +1,111 @@
+package csrf
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"new-version/internal/http/cookie"
+	mwAuth "new-version/internal/http/middleware/auth"
+	"new-version/pkg/json"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CookieName = "csrf_token"
+	HeaderName = "X-CSRF-Token"
+)
+
+// NewToken returns a random nonce signed with secret for the login session.
+// The same value is set as cookie and must be echoed back by the client in the
+// X-CSRF-Token header. Binding it to the session keeps a token issued to one
+// user, e.g. planted by a sibling subdomain, from being valid for another.
+func NewToken(secret string, session uuid.UUID) (string, error) {
+	const op = "middleware.csrf.NewToken"
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	n := base64.RawURLEncoding.EncodeToString(nonce)
+
+	return n + "." + sign(secret, session, n), nil
+}
+
+// ValidToken reports whether token was issued by NewToken for session.
+func ValidToken(secret string, session uuid.UUID, token string) bool {
+	n, sig, ok := strings.Cut(token, ".")
+	if !ok || n == "" || session == uuid.Nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(sig), []byte(sign(secret, session, n)))
+}
+
+func sign(secret string, session uuid.UUID, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(session.String()))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func SetCookie(w http.ResponseWriter, jar *cookie.Jar, token string) {
+	jar.Set(w, CookieName, token, 0)
+}
+
+func ClearCookie(w http.ResponseWriter, jar *cookie.Jar) {
+	jar.Clear(w, CookieName)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+
+	return false
+}
+
+// Csrf enforces the double-submit token on state-changing requests that
+// authenticate with the access_token cookie. Requests carrying a bearer token
+// can't be forged by a browser and are let through. The token has to belong to
+// the session of the caller, so Csrf must follow Auth.Require.
+func Csrf(secret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isSafeMethod(r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if _, fromCookie := mwAuth.TokenFromRequest(r); !fromCookie {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			c, err := r.Cookie(CookieName)
+			if err != nil || c.Value == "" {
+				json.WriteError(w, r, "missing csrf token", http.StatusForbidden)
+				return
+			}
+
+			p, _ := mwAuth.PrincipalFromContext(r.Context())
+
+			header := r.Header.Get(HeaderName)
+			if !hmac.Equal([]byte(header), []byte(c.Value)) || !ValidToken(secret, p.SessionId, header) {
+				json.WriteError(w, r, "invalid csrf token", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,77 +2,131 @@ package logger
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"time"
+
+	"new-version/internal/config"
 )
 
 type responseWriter struct {
 	http.ResponseWriter
-	bytes int
+	status int
+	bytes  int
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
 }
 
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
 	n, err := rw.ResponseWriter.Write(b)
 	rw.bytes += n
 	return n, err
 }
 
-func LoggerMiddleware(log *slog.Logger) func(http.Handler) http.Handler {
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// body counts the bytes of the request body read by the handler.
+type body struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (b *body) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += int64(n)
+	return n, err
+}
+
+// entry collects what's only known inside the handler chain.
+type entry struct {
+	userId string
+}
+
+type entryKey struct{}
 
-	middleware := func(next http.Handler) http.Handler {
+// SetUser records the authenticated user in the access log of the request.
+func SetUser(ctx context.Context, userId string) {
+	if e, ok := ctx.Value(entryKey{}).(*entry); ok {
+		e.userId = userId
+	}
+}
 
-		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+// Logger writes an access log record per request: failures at Error or
+// Warn, requests slower than the threshold at Warn and a sample of the
+// successful ones at Info.
+func Logger(log *slog.Logger, cfg *config.AccessLog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			ww := &responseWriter{ResponseWriter: w}
+			e := &entry{}
+
+			var in *body
+			if r.Body != nil && r.Body != http.NoBody {
+				in = &body{ReadCloser: r.Body}
+				r.Body = in
+			}
 
-			entry := log.With(
-				slog.String("method", r.Method),
-				slog.String("path", r.URL.Path),
-				slog.String("remote_addr", r.RemoteAddr),
-				slog.String("user_agent", r.UserAgent()),
-			)
+			r = r.WithContext(context.WithValue(r.Context(), entryKey{}, e))
 
-			tnow := time.Now()
+			start := time.Now()
 			defer func() {
-				entry.Info("request completed",
-					slog.String("duration", time.Since(tnow).String()),
-					slog.Int("bytes", ww.bytes),
+				elapsed := time.Since(start)
+
+				status := ww.status
+				if status == 0 {
+					status = http.StatusOK
+				}
+
+				level, msg := slog.LevelInfo, "request completed"
+				switch {
+				case status >= http.StatusInternalServerError:
+					level = slog.LevelError
+				case status >= http.StatusBadRequest:
+					level = slog.LevelWarn
+				case cfg.SlowThreshold > 0 && elapsed >= cfg.SlowThreshold:
+					level, msg = slog.LevelWarn, "slow request"
+				case rand.Float64() >= cfg.SampleRate:
+					return
+				}
+
+				var bytesIn int64
+				if in != nil {
+					bytesIn = in.bytes
+				}
+
+				route := r.Pattern
+				if route == "" {
+					route = "unmatched"
+				}
+
+				log.LogAttrs(r.Context(), level, msg,
+					slog.String("method", r.Method),
+					slog.String("route", route),
+					slog.String("path", r.URL.Path),
+					slog.Int("status", status),
+					slog.String("user_id", e.userId),
+					slog.Int64("bytes_in", bytesIn),
+					slog.Int("bytes_out", ww.bytes),
+					slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+					slog.String("remote_addr", r.RemoteAddr),
+					slog.String("user_agent", r.UserAgent()),
 				)
 			}()
 
 			next.ServeHTTP(ww, r)
 		})
-
-		return handler
-	}
-
-	return middleware
-}
-
-func Logger(ctx context.Context, w http.ResponseWriter, r *http.Request) bool {
-	log, ok := ctx.Value("logger").(*slog.Logger)
-	if !ok || log == nil {
-		http.Error(w, "internal server error (logger missing)", http.StatusInternalServerError)
-		return false
 	}
-	ww := &responseWriter{ResponseWriter: w}
-
-	entry := log.With(
-		slog.String("method", r.Method),
-		slog.String("path", r.URL.Path),
-		slog.String("remote_addr", r.RemoteAddr),
-		slog.String("user_agent", r.UserAgent()),
-	)
-
-	tnow := time.Now()
-	entry.Info("request started")
-
-	defer func() {
-		entry.Info("request completed",
-			slog.String("duration", time.Since(tnow).String()),
-			slog.Int("bytes", ww.bytes),
-		)
-	}()
-
-	return true
 }
@@ -0,0 +1,242 @@
+package ratelimit
+
+import (
+	"container/list"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mwAuth "new-version/internal/http/middleware/auth"
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+
+	"github.com/google/uuid"
+)
+
+// sweepInterval is how often buckets that refilled completely are dropped.
+const sweepInterval = time.Minute
+
+// DefaultMaxKeys is the number of buckets a Limiter keeps by default.
+const DefaultMaxKeys = 100_000
+
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a set of token buckets, one per key, holding up to burst
+// tokens and refilled at perMinute tokens a minute. It keeps at most maxKeys
+// buckets, evicting the least recently used one for a new key, so clients
+// rotating addresses can't grow it without bound. An evicted client starts
+// over with a full bucket.
+type Limiter struct {
+	rate    float64 // tokens per second
+	burst   int
+	maxKeys int
+
+	mu        sync.Mutex
+	buckets   map[string]*list.Element // of *bucket
+	recent    *list.List               // most recently used first
+	lastSweep time.Time
+}
+
+// New returns a limiter. maxKeys of 0 or less means DefaultMaxKeys.
+func New(perMinute int, burst int, maxKeys int) *Limiter {
+	if maxKeys <= 0 {
+		maxKeys = DefaultMaxKeys
+	}
+
+	return &Limiter{
+		rate:    float64(perMinute) / 60,
+		burst:   max(burst, 1),
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Len returns the number of buckets kept.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return len(l.buckets)
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is the wait for the next token when the request isn't allowed.
+	RetryAfter time.Duration
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+}
+
+// Allow takes a token from the bucket of key.
+func (l *Limiter) Allow(key string) Result {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b := l.bucket(key, now)
+
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := Result{Limit: l.burst}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.wait(1 - b.tokens)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = l.wait(float64(l.burst) - b.tokens)
+
+	return res
+}
+
+// wait returns how long it takes to refill n tokens.
+func (l *Limiter) wait(n float64) time.Duration {
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(n / l.rate * float64(time.Second))
+}
+
+// bucket returns the bucket of key, marked as the most recently used.
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if e, ok := l.buckets[key]; ok {
+		l.recent.MoveToFront(e)
+		return e.Value.(*bucket)
+	}
+
+	if len(l.buckets) >= l.maxKeys {
+		oldest := l.recent.Back()
+		l.recent.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*bucket).key)
+	}
+
+	b := &bucket{key: key, tokens: float64(l.burst), last: now}
+	l.buckets[key] = l.recent.PushFront(b)
+
+	return b
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, e := range l.buckets {
+		b := e.Value.(*bucket)
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			l.recent.Remove(e)
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Key identifies the caller: the user when the request was authenticated by
+// an earlier middleware, the client IP otherwise.
+func Key(r *http.Request) string {
+	if p, ok := mwAuth.PrincipalFromContext(r.Context()); ok {
+		return "user:" + p.UserId.String()
+	}
+
+	return "ip:" + hp.ClientIP(r)
+}
+
+// CallerKey is Key for middlewares that run before Auth.Require: it
+// identifies the user by the access token of the request if it's valid.
+// Whether the session is still active isn't checked, a revoked token still
+// counts against its user.
+func CallerKey(r *http.Request, jwtSecret string) string {
+	if key := Key(r); strings.HasPrefix(key, "user:") {
+		return key
+	}
+
+	if p, err := mwAuth.PrincipalFromRequest(r, jwtSecret); err == nil && p.UserId != uuid.Nil {
+		return "user:" + p.UserId.String()
+	}
+
+	return "ip:" + hp.ClientIP(r)
+}
+
+// seconds rounds d up to whole seconds, as the headers expect.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// Limit rejects requests over the limit of l with 429 Too Many Requests. It
+// sets the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+// on every response and Retry-After on rejected ones. A nil l disables
+// limiting.
+func Limit(l *Limiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if l == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serve(w, r, next, l, Key(r))
+		})
+	}
+}
+
+// Caller is Limit for every route, before authentication. Requests with a
+// valid access token are limited by user with authenticated, so users behind
+// a shared address don't use up each other's requests, and the others by
+// client IP with anonymous. Nil limiters let their requests through.
+func Caller(anonymous *Limiter, authenticated *Limiter, jwtSecret string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if anonymous == nil && authenticated == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := CallerKey(r, jwtSecret)
+
+			l := anonymous
+			if strings.HasPrefix(key, "user:") {
+				l = authenticated
+			}
+
+			if l == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			serve(w, r, next, l, key)
+		})
+	}
+}
+
+func serve(w http.ResponseWriter, r *http.Request, next http.Handler, l *Limiter, key string) {
+	res := l.Allow(key)
+
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", seconds(res.Reset))
+
+	if !res.Allowed {
+		h.Set("Retry-After", seconds(res.RetryAfter))
+		json.WriteError(w, r, "too many requests", http.StatusTooManyRequests)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
@@ -0,0 +1,50 @@
+package requestid
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/logger"
+)
+
+const maxLength = 128
+
+// RequestID gives every request an id, returned in the X-Request-ID header
+// and in error responses, so that a failure can be found in the logs. An id
+// sent by the client or a proxy is kept when it's safe to log.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(hp.HeaderRequestID)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+
+		ctx := hp.WithRequestID(r.Context(), id)
+		ctx = logger.WithAttrs(ctx, slog.String(logger.KeyRequestID, id))
+
+		w.Header().Set(hp.HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// valid accepts ids of letters, digits and the separators used by common
+// tracing formats.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
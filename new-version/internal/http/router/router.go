@@ -0,0 +1,105 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwChain "new-version/internal/http/middleware/chain"
+)
+
+// Middlewares are the shared middlewares handlers pick for their routes.
+// RateLimit limits authenticated users and must follow Auth.Require,
+// AuthRateLimit is the stricter limit of the login and registration routes.
+// CatalogCache sets the Cache-Control policy of public catalog reads.
+type Middlewares struct {
+	Auth          *mwAuth.Auth
+	Csrf          mwChain.Middleware
+	RateLimit     mwChain.Middleware
+	AuthRateLimit mwChain.Middleware
+	CatalogCache  mwChain.Middleware
+}
+
+// Group registers routes that share middlewares and a path prefix.
+type Group struct {
+	mux         *http.ServeMux
+	prefix      string
+	middlewares []mwChain.Middleware
+}
+
+// Group returns a group whose routes run the middlewares of g followed by
+// middlewares.
+func (g *Group) Group(middlewares ...mwChain.Middleware) *Group {
+	return g.Mount("", middlewares...)
+}
+
+// Mount returns a group like Group whose routes are also under prefix.
+func (g *Group) Mount(prefix string, middlewares ...mwChain.Middleware) *Group {
+	return &Group{
+		mux:         g.mux,
+		prefix:      g.prefix + prefix,
+		middlewares: append(slices.Clone(g.middlewares), middlewares...),
+	}
+}
+
+// Handle registers handler for pattern, wrapped in the middlewares of the
+// group and then the route's own middlewares.
+func (g *Group) Handle(pattern string, handler http.Handler, middlewares ...mwChain.Middleware) {
+	all := append(slices.Clone(g.middlewares), middlewares...)
+	g.mux.Handle(g.prefixed(pattern), mwChain.Chain(handler, all...))
+}
+
+// prefixed puts the prefix of g in front of the path of pattern, after its
+// method if there is one.
+func (g *Group) prefixed(pattern string) string {
+	if g.prefix == "" {
+		return pattern
+	}
+
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		return method + " " + g.prefix + strings.TrimLeft(path, " ")
+	}
+
+	return g.prefix + pattern
+}
+
+func (g *Group) HandleFunc(pattern string, handler http.HandlerFunc, middlewares ...mwChain.Middleware) {
+	g.Handle(pattern, handler, middlewares...)
+}
+
+// Router owns the mux. Its global middlewares run for every request,
+// including ones that match no route.
+type Router struct {
+	root   *Group
+	global []mwChain.Middleware
+}
+
+func New() *Router {
+	return &Router{root: &Group{mux: http.NewServeMux()}}
+}
+
+// Use adds global middlewares.
+func (r *Router) Use(middlewares ...mwChain.Middleware) {
+	r.global = append(r.global, middlewares...)
+}
+
+// Group returns a group of routes that run middlewares.
+func (r *Router) Group(middlewares ...mwChain.Middleware) *Group {
+	return r.root.Group(middlewares...)
+}
+
+func (r *Router) Handle(pattern string, handler http.Handler, middlewares ...mwChain.Middleware) {
+	r.root.Handle(pattern, handler, middlewares...)
+}
+
+func (r *Router) HandleFunc(pattern string, handler http.HandlerFunc, middlewares ...mwChain.Middleware) {
+	r.root.Handle(pattern, handler, middlewares...)
+}
+
+// Handler returns the router wrapped in the global middlewares. Routes added
+// afterwards are served as well, global middlewares added afterwards aren't
+// applied.
+func (r *Router) Handler() http.Handler {
+	return mwChain.Chain(r.root.mux, r.global...)
+}
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	mwChain "new-version/internal/http/middleware/chain"
+)
+
+// Version is a release of the API served under its own path prefix. A
+// version scheduled for removal has Deprecated set, and Sunset once the
+// removal date is known; its responses then carry the Deprecation and Sunset
+// headers and link to the same path under Successor.
+type Version struct {
+	Prefix     string
+	Deprecated time.Time
+	Sunset     time.Time
+	Successor  string
+}
+
+// Version returns the group of the routes of v. Each version registers its
+// own handlers on it.
+func (r *Router) Version(v Version, middlewares ...mwChain.Middleware) *Group {
+	if !v.Deprecated.IsZero() || !v.Sunset.IsZero() {
+		middlewares = append([]mwChain.Middleware{Deprecate(v)}, middlewares...)
+	}
+
+	return r.root.Mount(v.Prefix, middlewares...)
+}
+
+// Deprecate sets the headers of RFC 9745 and RFC 8594 announcing that v is
+// going away.
+func Deprecate(v Version) mwChain.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+
+			if !v.Deprecated.IsZero() {
+				h.Set("Deprecation", "@"+strconv.FormatInt(v.Deprecated.Unix(), 10))
+			}
+
+			if !v.Sunset.IsZero() {
+				h.Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+			}
+
+			if v.Successor != "" {
+				path := v.Successor + strings.TrimPrefix(r.URL.Path, v.Prefix)
+				h.Add("Link", "<"+path+`>; rel="successor-version"`)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
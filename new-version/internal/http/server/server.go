@@ -1,15 +1,47 @@
 package httpserver
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/http/pprof"
+	"new-version/internal/certs"
 	"new-version/internal/config"
+	"new-version/internal/http/cookie"
+	accountHdl "new-version/internal/http/handler/account"
+	auditHdl "new-version/internal/http/handler/audit"
 	bookCatHdl "new-version/internal/http/handler/bookcategory"
+	healthHdl "new-version/internal/http/handler/health"
+	impHdl "new-version/internal/http/handler/impersonation"
+	legacyHdl "new-version/internal/http/handler/legacy"
 	userHdl "new-version/internal/http/handler/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCache "new-version/internal/http/middleware/cache"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+	mwLog "new-version/internal/http/middleware/logger"
+	mwRateLimit "new-version/internal/http/middleware/ratelimit"
+	mwRealIp "new-version/internal/http/middleware/realip"
+	mwRequestId "new-version/internal/http/middleware/requestid"
+	"new-version/internal/http/router"
+	"new-version/internal/metrics"
+	accountRepo "new-version/internal/repository/account"
+	auditRepo "new-version/internal/repository/audit"
 	bookCatRepo "new-version/internal/repository/bookcategory"
+	schemaRepo "new-version/internal/repository/schema"
+	sessionRepo "new-version/internal/repository/session"
 	userRepo "new-version/internal/repository/user"
+	accountSvc "new-version/internal/service/account"
+	auditSvc "new-version/internal/service/audit"
 	authSvc "new-version/internal/service/auth"
+	bookCatSvc "new-version/internal/service/bookcategory"
+	healthSvc "new-version/internal/service/health"
+	impSvc "new-version/internal/service/impersonation"
+	sessionSvc "new-version/internal/service/session"
 	userSvc "new-version/internal/service/user"
+	"new-version/internal/tracing"
+	userVal "new-version/internal/validator/user"
+	"time"
 
 	"new-version/internal/storage/postgres"
 
@@ -19,34 +51,245 @@ import (
 	_ "new-version/docs"
 )
 
-func New(log *slog.Logger, cfg *config.Config, stg *postgres.Storage) *http.Server {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/swagger/", swagger.WrapHandler)
+// routes is implemented by the handlers of a version.
+type routes interface {
+	RegisterRoutes(rt *router.Group, mw *router.Middlewares)
+}
+
+// Workers runs the background jobs of the services, see app.App.
+type Workers interface {
+	AddWorker(name string, run func(ctx context.Context))
+}
+
+func New(
+	log *slog.Logger,
+	cfg *config.Config,
+	stg *postgres.Storage,
+	m *metrics.Metrics,
+	workers Workers,
+) (*http.Server, error) {
+	const op = "http.server.New"
+
+	hasher, err := authSvc.NewHasher(&cfg.Hashing)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	trustedProxies, err := mwRealIp.ParseTrusted(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	// nil limiters let every request through
+	var ipLimiter, userLimiter, authLimiter *mwRateLimit.Limiter
+	if rl := cfg.RateLimit; rl.Enabled {
+		ipLimiter = mwRateLimit.New(rl.IPPerMinute, rl.IPBurst, rl.MaxKeys)
+		userLimiter = mwRateLimit.New(rl.UserPerMinute, rl.UserBurst, rl.MaxKeys)
+		authLimiter = mwRateLimit.New(rl.AuthPerMinute, rl.AuthBurst, rl.MaxKeys)
+	}
+
+	cookies, err := cookie.New(&cfg.Cookie)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
 
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
-		AllowedHeaders:   []string{"*"},
-		ExposedHeaders:   []string{"Content-Length"},
-		AllowCredentials: true,
+		AllowedOrigins: cfg.CORS.AllowedOrigins,
+		AllowedMethods: cfg.CORS.AllowedMethods,
+		AllowedHeaders: cfg.CORS.AllowedHeaders,
+		ExposedHeaders: []string{
+			"Content-Length", "X-Request-ID",
+			"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After",
+			"Deprecation", "Sunset", "Link", "ETag", "Last-Modified",
+		},
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           int(cfg.CORS.MaxAge.Seconds()),
 	})
-	handler := c.Handler(mux)
+
+	rt := router.New()
+	// the metrics and Route read the pattern the mux sets on the request, so
+	// the middlewares after them must not copy it
+	rt.Use(
+		mwRequestId.RequestID,
+		mwRealIp.RealIP(trustedProxies),
+		tracing.Middleware,
+		mwLog.Logger(log, &cfg.AccessLog),
+		c.Handler,
+		m.Middleware,
+		tracing.Route,
+		mwRateLimit.Caller(ipLimiter, userLimiter, cfg.JwtSecret),
+	)
+	rt.HandleFunc("/swagger/", swagger.WrapHandler)
+
+	hSvc := healthSvc.New(log, stg.DB(), schemaRepo.New(stg.DB()), postgres.SchemaVersion, cfg.StoragePath)
+	healthHdl.New(log, hSvc).RegisterRoutes(rt.Group())
+
+	adRepo := auditRepo.New(stg.DB())
+	adSvc := auditSvc.New(log, adRepo)
+
+	sRepo := sessionRepo.New(stg.DB())
+	sSvc := sessionSvc.New(log, sRepo, adSvc)
+
+	mw := &router.Middlewares{
+		Auth: mwAuth.New(cfg.JwtSecret, sSvc),
+		Csrf: mwCsrf.Csrf(cfg.JwtSecret),
+
+		AuthRateLimit: mwRateLimit.Limit(authLimiter),
+		CatalogCache:  mwCache.Control(mwCache.Public(cfg.Cache.CatalogMaxAge)),
+	}
+
+	adHandler := auditHdl.New(log, adSvc, &cfg.Pagination)
 
 	bcRepo := bookCatRepo.New(stg.DB())
-	bcHandler := bookCatHdl.New(log, bcRepo, &cfg.Security)
-	bcHandler.RegisterRoutes(mux, log, &cfg.Security)
+	bcSvc := bookCatSvc.New(log, bcRepo, adSvc)
+	bcHandler := bookCatHdl.New(log, bcSvc)
 
-	aSvc := authSvc.New(log, &cfg.Security)
+	aSvc := authSvc.New(log, &cfg.Security, hasher)
 	uRepo := userRepo.New(stg.DB())
-	uSrv := userSvc.New(log, uRepo, aSvc, &cfg.Security)
-	uHandler := userHdl.New(log, uSrv, &cfg.Security)
-	uHandler.RegisterRoutes(mux, log, &cfg.Security)
 
-	return &http.Server{
+	var authn authSvc.Authenticator = authSvc.NewLocal(log, uRepo, aSvc)
+	if cfg.LDAP.Enabled {
+		authn = authSvc.NewChain(log, authSvc.NewLDAP(log, &cfg.LDAP, nil), authn)
+	}
+
+	policy, err := userVal.NewPasswordPolicy(&cfg.PasswordPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	uSrv := userSvc.New(log, uRepo, aSvc, authn, sSvc, adSvc, policy, m, &cfg.Security)
+	uHandler := userHdl.New(log, uSrv, sSvc, &cfg.Security, cookies)
+
+	imSvc := impSvc.New(log, uRepo, aSvc, sSvc, adSvc, &cfg.Security)
+	imHandler := impHdl.New(log, imSvc)
+
+	acSvc := accountSvc.New(log, accountRepo.New(stg.DB()), uRepo, adSvc, &cfg.Privacy)
+	acHandler := accountHdl.New(log, acSvc)
+
+	v2 := []struct {
+		group string
+		routes
+	}{
+		{group: "audit", routes: adHandler},
+		{group: "book_category", routes: bcHandler},
+		{group: "user", routes: uHandler},
+		{group: "impersonation", routes: imHandler},
+		{group: "account", routes: acHandler},
+	}
+
+	// every group limits its authenticated routes with buckets of its own,
+	// shared by its routes in all versions
+	withGroupLimit := func(group string) *router.Middlewares {
+		gm := *mw
+		if rl := cfg.RateLimit; rl.Enabled {
+			rate := rl.Group(group)
+			gm.RateLimit = mwRateLimit.Limit(mwRateLimit.New(rate.PerMinute, rate.Burst, rl.MaxKeys))
+		} else {
+			gm.RateLimit = mwRateLimit.Limit(nil)
+		}
+
+		return &gm
+	}
+	v2Groups := []*router.Group{rt.Version(router.Version{Prefix: "/api/v2"})}
+
+	// the routes from before versioning, kept for clients not yet on /api/v2
+	if u := cfg.API.Unversioned; u.Enabled {
+		v2Groups = append(v2Groups, rt.Version(router.Version{
+			Deprecated: u.Deprecated,
+			Sunset:     u.Sunset,
+			Successor:  "/api/v2",
+		}))
+	}
+
+	for _, h := range v2 {
+		hm := withGroupLimit(h.group)
+		for _, g := range v2Groups {
+			h.RegisterRoutes(g, hm)
+		}
+	}
+
+	// the Django API, its paths don't map to /api/v2 so there's no successor
+	if v1 := cfg.API.V1; v1.Enabled {
+		lgHandler := legacyHdl.New(log, uSrv, sSvc, bcSvc, &cfg.Security)
+		lgHandler.RegisterRoutes(rt.Version(router.Version{
+			Prefix:     "/api/v1",
+			Deprecated: v1.Deprecated,
+			Sunset:     v1.Sunset,
+		}), withGroupLimit("legacy"))
+	}
+
+	// purges accounts whose deletion grace period is over
+	workers.AddWorker("account-purge", acSvc.RunPurge)
+
+	srv := &http.Server{
 		Addr:         cfg.Address,
-		Handler:      handler,
+		Handler:      rt.Handler(),
 		WriteTimeout: cfg.Timeout,
 		ReadTimeout:  cfg.Timeout,
 		IdleTimeout:  cfg.IdleTimeout,
 	}
+
+	if cfg.HTTPServer.TLS.Enabled {
+		reloader, err := certs.NewReloader(log, cfg.HTTPServer.TLS.CertFile, cfg.HTTPServer.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		srv.TLSConfig, err = certs.ServerConfig(&cfg.HTTPServer.TLS, reloader)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		workers.AddWorker("tls-reload", reloader.Watch(cfg.HTTPServer.TLS.ReloadInterval))
+	}
+
+	return srv, nil
+}
+
+// NewAdmin returns the server of the operational endpoints, listening on
+// its own address so they aren't exposed with the API. With TLS enabled it
+// only accepts clients with a certificate signed by the configured CA, which
+// Config.Validate requires outside local. The admin API, like the audit log
+// and impersonation, stays on the public listener: its routes authorize the
+// user by access token, which a client certificate doesn't identify.
+func NewAdmin(
+	log *slog.Logger,
+	cfg *config.Config,
+	m *metrics.Metrics,
+	workers Workers,
+) (*http.Server, error) {
+	const op = "http.server.NewAdmin"
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	srv := &http.Server{
+		Addr:        cfg.Admin.Address,
+		Handler:     mux,
+		ReadTimeout: cfg.Timeout,
+		// profiles and traces take as long as the seconds parameter asks
+		WriteTimeout: max(cfg.Timeout, 60*time.Second),
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	if cfg.Admin.TLS.Enabled {
+		reloader, err := certs.NewReloader(log, cfg.Admin.TLS.CertFile, cfg.Admin.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		srv.TLSConfig, err = certs.MutualConfig(&cfg.Admin.TLS, reloader, cfg.Admin.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		workers.AddWorker("admin-tls-reload", reloader.Watch(cfg.Admin.TLS.ReloadInterval))
+	}
+
+	return srv, nil
 }
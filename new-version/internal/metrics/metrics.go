@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "library"
+
+// OverdueCounter counts the loans past their due date. It's queried on
+// every scrape.
+type OverdueCounter interface {
+	CountOverdue(ctx context.Context) (int, error)
+}
+
+// Metrics holds the collectors of the application. The methods recording
+// business events are safe to call on a nil *Metrics, so services work
+// without metrics in tests.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	latency  *prometheus.HistogramVec
+
+	registrations prometheus.Counter
+	logins        *prometheus.CounterVec
+}
+
+func New(log *slog.Logger, db *sql.DB, overdue OverdueCounter) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by method, route and status.",
+		}, []string{"method", "route", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by method, route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "registrations_total",
+			Help:      "Registered users.",
+		}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts by outcome.",
+		}, []string{"outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		collectors.NewDBStatsCollector(db, "postgres"),
+		m.requests,
+		m.latency,
+		m.registrations,
+		m.logins,
+		&overdueCollector{log: log, counter: overdue, desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "overdue_loans"),
+			"Active loans past their due date.",
+			nil, nil,
+		)},
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry:      m.registry,
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Middleware counts requests by their route pattern, which keeps the label
+// cardinality bounded. It reads the pattern the mux sets on the request, so
+// it must be the innermost global middleware.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		start := time.Now()
+
+		next.ServeHTTP(sw, r)
+
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+
+		labels := prometheus.Labels{"method": r.Method, "route": route, "status": strconv.Itoa(status)}
+		m.requests.With(labels).Inc()
+		m.latency.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (m *Metrics) Registered() {
+	if m != nil {
+		m.registrations.Inc()
+	}
+}
+
+// LoggedIn counts a login attempt, failed when err isn't nil.
+func (m *Metrics) LoggedIn(err error) {
+	if m == nil {
+		return
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	m.logins.WithLabelValues(outcome).Inc()
+}
+
+type overdueCollector struct {
+	log     *slog.Logger
+	counter OverdueCounter
+	desc    *prometheus.Desc
+}
+
+func (c *overdueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *overdueCollector) Collect(ch chan<- prometheus.Metric) {
+	const op = "metrics.overdueCollector.Collect"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	n, err := c.counter.CountOverdue(ctx)
+	if err != nil {
+		c.log.Error("failed to count overdue loans", slog.String("op", op), slog.String("error", err.Error()))
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
@@ -0,0 +1,270 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"new-version/internal/contract/account"
+	"new-version/pkg/errs"
+)
+
+type Repository interface {
+	GetReservations(ctx context.Context, userId uuid.UUID) ([]account.Reservation, error)
+	GetReviews(ctx context.Context, userId uuid.UUID) ([]account.Review, error)
+	GetNotifications(ctx context.Context, userId uuid.UUID) ([]account.Notification, error)
+	CountOutstandingLoans(ctx context.Context, userId uuid.UUID) (int, error)
+	RequestDeletion(ctx context.Context, userId uuid.UUID, at time.Time) (time.Time, error)
+	CancelDeletion(ctx context.Context, userId uuid.UUID) error
+	GetDueForDeletion(ctx context.Context, requestedBefore time.Time) ([]uuid.UUID, error)
+	Purge(ctx context.Context, userId uuid.UUID) error
+}
+
+type DefaultRepository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+func (a *DefaultRepository) GetReservations(ctx context.Context, userId uuid.UUID) ([]account.Reservation, error) {
+	const op = "modules.account.repository.GetReservations"
+
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT id, book_id, quantity, status, reserved_at, due_date, returned_date
+		FROM reservations WHERE owner_id = $1 ORDER BY reserved_at`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	reservations := []account.Reservation{}
+
+	for rows.Next() {
+		var (
+			res                   account.Reservation
+			dueDate, returnedDate sql.NullTime
+		)
+
+		if err := rows.Scan(&res.Id, &res.BookId, &res.Quantity, &res.Status, &res.ReservedAt, &dueDate, &returnedDate); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if dueDate.Valid {
+			res.DueDate = &dueDate.Time
+		}
+		if returnedDate.Valid {
+			res.ReturnedDate = &returnedDate.Time
+		}
+
+		reservations = append(reservations, res)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reservations, nil
+}
+
+func (a *DefaultRepository) GetReviews(ctx context.Context, userId uuid.UUID) ([]account.Review, error) {
+	const op = "modules.account.repository.GetReviews"
+
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT id, book_id, rating FROM reviews WHERE author_id = $1 ORDER BY id`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	reviews := []account.Review{}
+
+	for rows.Next() {
+		var (
+			rev    account.Review
+			rating sql.NullInt64
+		)
+
+		if err := rows.Scan(&rev.Id, &rev.BookId, &rating); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if rating.Valid {
+			r := int(rating.Int64)
+			rev.Rating = &r
+		}
+
+		reviews = append(reviews, rev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return reviews, nil
+}
+
+func (a *DefaultRepository) GetNotifications(ctx context.Context, userId uuid.UUID) ([]account.Notification, error) {
+	const op = "modules.account.repository.GetNotifications"
+
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT id, title, message FROM notifications WHERE recipient_id = $1 ORDER BY id`, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	notifications := []account.Notification{}
+
+	for rows.Next() {
+		var (
+			n     account.Notification
+			title sql.NullString
+		)
+
+		if err := rows.Scan(&n.Id, &title, &n.Message); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		n.Title = title.String
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return notifications, nil
+}
+
+// CountOutstandingLoans counts the active reservations whose books haven't
+// been returned yet.
+func (a *DefaultRepository) CountOutstandingLoans(ctx context.Context, userId uuid.UUID) (int, error) {
+	const op = "modules.account.repository.CountOutstandingLoans"
+
+	var n int
+
+	row := a.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM reservations WHERE owner_id = $1 AND status = 'active' AND returned_date IS NULL`, userId)
+	if err := row.Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
+
+// RequestDeletion marks the user for deletion and returns the time of the
+// request. Repeated requests keep the first time.
+func (a *DefaultRepository) RequestDeletion(ctx context.Context, userId uuid.UUID, at time.Time) (time.Time, error) {
+	const op = "modules.account.repository.RequestDeletion"
+
+	var requestedAt time.Time
+
+	row := a.db.QueryRowContext(ctx,
+		`UPDATE users SET deletion_requested_at = COALESCE(deletion_requested_at, $1)
+		WHERE id = $2 RETURNING deletion_requested_at`, at, userId)
+	if err := row.Scan(&requestedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return time.Time{}, fmt.Errorf("%s: %w", op, errs.NotFound("user with this id does not exist"))
+		}
+
+		return time.Time{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return requestedAt, nil
+}
+
+func (a *DefaultRepository) CancelDeletion(ctx context.Context, userId uuid.UUID) error {
+	const op = "modules.account.repository.CancelDeletion"
+
+	res, err := a.db.ExecContext(ctx,
+		`UPDATE users SET deletion_requested_at = NULL WHERE id = $1 AND deletion_requested_at IS NOT NULL`, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.Conflict("account is not scheduled for deletion"))
+	}
+
+	return nil
+}
+
+func (a *DefaultRepository) GetDueForDeletion(ctx context.Context, requestedBefore time.Time) ([]uuid.UUID, error) {
+	const op = "modules.account.repository.GetDueForDeletion"
+
+	rows, err := a.db.QueryContext(ctx,
+		`SELECT id FROM users WHERE deletion_requested_at IS NOT NULL AND deletion_requested_at <= $1`, requestedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return ids, nil
+}
+
+// Purge detaches the user's reviews and deletes the user together with
+// everything else that belongs to them. Users that withdrew the deletion
+// request in the meantime are left alone.
+func (a *DefaultRepository) Purge(ctx context.Context, userId uuid.UUID) error {
+	const op = "modules.account.repository.Purge"
+
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`UPDATE reviews SET author_id = NULL WHERE author_id = $1`,
+		`DELETE FROM notifications WHERE recipient_id = $1`,
+		`DELETE FROM reservations WHERE owner_id = $1`,
+		`DELETE FROM sessions WHERE user_id = $1`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt, userId); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1 AND deletion_requested_at IS NOT NULL`, userId)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.Conflict("account is not scheduled for deletion"))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
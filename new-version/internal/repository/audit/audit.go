@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"new-version/internal/contract/audit"
+)
+
+type Repository interface {
+	Create(ctx context.Context, event audit.Event) error
+	GetList(ctx context.Context, filter audit.Filter) ([]audit.Event, error)
+}
+
+type DefaultRepository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+func (a *DefaultRepository) Create(ctx context.Context, event audit.Event) error {
+	const op = "modules.audit.repository.Create"
+
+	_, err := a.db.ExecContext(ctx,
+		`INSERT INTO audit_events(occurred_at, actor, impersonator, action, target, ip, user_agent, outcome, details)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		event.OccurredAt, event.Actor, event.Impersonator, event.Action, event.Target,
+		event.IP, event.UserAgent, event.Outcome, event.Details,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// GetList returns events matching filter, newest first. Zero-valued filter
+// fields are ignored, a zero Limit returns every matching event.
+func (a *DefaultRepository) GetList(ctx context.Context, filter audit.Filter) ([]audit.Event, error) {
+	const op = "modules.audit.repository.GetList"
+
+	var (
+		conds []string
+		args  []any
+	)
+
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if filter.Actor != "" {
+		where("actor = $%d", filter.Actor)
+	}
+	if filter.Impersonator != "" {
+		where("impersonator = $%d", filter.Impersonator)
+	}
+	if filter.Action != "" {
+		where("action = $%d", filter.Action)
+	}
+	if filter.Target != "" {
+		where("target = $%d", filter.Target)
+	}
+	if filter.Outcome != "" {
+		where("outcome = $%d", filter.Outcome)
+	}
+	if !filter.From.IsZero() {
+		where("occurred_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		where("occurred_at < $%d", filter.To)
+	}
+
+	query := `SELECT id, occurred_at, actor, impersonator, action, target, ip, user_agent, outcome, details FROM audit_events`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY occurred_at DESC, id DESC`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit, filter.Offset)
+		query += fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+	}
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+
+	for rows.Next() {
+		var e audit.Event
+		err := rows.Scan(&e.Id, &e.OccurredAt, &e.Actor, &e.Impersonator, &e.Action, &e.Target, &e.IP, &e.UserAgent, &e.Outcome, &e.Details)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
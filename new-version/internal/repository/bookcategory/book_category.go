@@ -6,20 +6,23 @@ import (
 	"errors"
 	"fmt"
 	"new-version/internal/contract/bookcategory"
-
-	"modernc.org/sqlite"
-	sqlite3 "modernc.org/sqlite/lib"
+	"new-version/internal/storage/postgres"
+	"new-version/pkg/errs"
 )
 
 type Repository interface {
 	GetById(ctx context.Context, id int) (bookcategory.Response, error)
 	Create(ctx context.Context, bookCat bookcategory.Request) (int, error)
-	DeleteById(ctx context.Context, id int) error
-	UpdateById(ctx context.Context, bookCat bookcategory.Request, id int) error
+	DeleteById(ctx context.Context, id int, version int) error
+	UpdateById(ctx context.Context, bookCat bookcategory.Request, id int, version int) error
 	GetByTitle(ctx context.Context, title string) (bookcategory.Response, error)
 	GetList(ctx context.Context) ([]bookcategory.Response, error)
 }
 
+// selectColumns lists the columns of a Response. Rows that were never
+// updated report their creation time as update time.
+const selectColumns = `SELECT id, title, created_at, COALESCE(updated_at, created_at), version FROM book_categories`
+
 type DefaultRepository struct {
 	db *sql.DB
 }
@@ -31,14 +34,14 @@ func New(db *sql.DB) *DefaultRepository {
 func (b *DefaultRepository) GetById(ctx context.Context, id int) (bookcategory.Response, error) {
 	const op = "modules.bookcategory.repository.GetById"
 
-	row := b.db.QueryRowContext(ctx, `SELECT * FROM book_categories WHERE id = $1`, id)
+	row := b.db.QueryRowContext(ctx, selectColumns+` WHERE id = $1`, id)
 
 	var bookCat bookcategory.Response
 
-	err := row.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime)
+	err := row.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime, &bookCat.UpdatedTime, &bookCat.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return bookcategory.Response{}, fmt.Errorf("%s: no book category category with id = %d", op, id)
+			return bookcategory.Response{}, fmt.Errorf("%s: %w", op, errs.NotFound("no book category with id = %d", id))
 		}
 
 		return bookcategory.Response{}, fmt.Errorf("%s: %w", op, err)
@@ -50,14 +53,14 @@ func (b *DefaultRepository) GetById(ctx context.Context, id int) (bookcategory.R
 func (b *DefaultRepository) GetByTitle(ctx context.Context, title string) (bookcategory.Response, error) {
 	const op = "modules.bookcategory.repository.GetByTitle"
 
-	row := b.db.QueryRowContext(ctx, `SELECT * FROM book_categories WHERE title = $1`, title)
+	row := b.db.QueryRowContext(ctx, selectColumns+` WHERE title = $1`, title)
 
 	var bookCat bookcategory.Response
 
-	err := row.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime)
+	err := row.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime, &bookCat.UpdatedTime, &bookCat.Version)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return bookcategory.Response{}, fmt.Errorf("%s: no book category category with title = %s", op, title)
+			return bookcategory.Response{}, fmt.Errorf("%s: %w", op, errs.NotFound("no book category with title = %s", title))
 		}
 
 		return bookcategory.Response{}, fmt.Errorf("%s: %w", op, err)
@@ -77,8 +80,8 @@ func (b *DefaultRepository) Create(ctx context.Context, bookCat bookcategory.Req
 		bookCat.Title,
 	).Scan(&id)
 	if err != nil {
-		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
-			return 0, fmt.Errorf("%s: book category with title '%s' already exists", op, bookCat.Title)
+		if postgres.IsUniqueViolation(err) {
+			return 0, fmt.Errorf("%s: %w", op, errs.Conflict("book category with title '%s' already exists", bookCat.Title))
 		}
 
 		return 0, fmt.Errorf("%s: %w", op, err)
@@ -87,25 +90,35 @@ func (b *DefaultRepository) Create(ctx context.Context, bookCat bookcategory.Req
 	return id, nil
 }
 
-func (b *DefaultRepository) UpdateById(ctx context.Context, bookCat bookcategory.Request, id int) error {
+// UpdateById replaces the category if it's still at version and bumps the
+// version.
+func (b *DefaultRepository) UpdateById(ctx context.Context, bookCat bookcategory.Request, id int, version int) error {
 	const op = "modules.bookcategory.repository.Update"
 
-	_, err := b.db.ExecContext(ctx, `UPDATE book_categories SET title = $1 WHERE id = $2`, bookCat.Title, id)
+	res, err := b.db.ExecContext(
+		ctx,
+		`UPDATE book_categories SET title = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND version = $3`,
+		bookCat.Title, id, version,
+	)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: no book category category with id = %d", op, id)
+		if postgres.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, errs.Conflict("book category with title '%s' already exists", bookCat.Title))
 		}
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, b.missingOrStale(ctx, id))
+	}
+
 	return nil
 }
 
 func (b *DefaultRepository) GetList(ctx context.Context) ([]bookcategory.Response, error) {
 	const op = "modules.bookcategory.repository.GetList"
 
-	rows, err := b.db.QueryContext(ctx, `SELECT * FROM book_categories`)
+	rows, err := b.db.QueryContext(ctx, selectColumns+` ORDER BY id`)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -114,7 +127,7 @@ func (b *DefaultRepository) GetList(ctx context.Context) ([]bookcategory.Respons
 
 	for rows.Next() {
 		var bookCat bookcategory.Response
-		err := rows.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime)
+		err := rows.Scan(&bookCat.Id, &bookCat.Title, &bookCat.CreatedTime, &bookCat.UpdatedTime, &bookCat.Version)
 		if err != nil {
 			return nil, fmt.Errorf("%s: %w", op, err)
 		}
@@ -125,17 +138,39 @@ func (b *DefaultRepository) GetList(ctx context.Context) ([]bookcategory.Respons
 	return bookCatList, nil
 }
 
-func (b *DefaultRepository) DeleteById(ctx context.Context, id int) error {
+// DeleteById deletes the category if it's still at version.
+func (b *DefaultRepository) DeleteById(ctx context.Context, id int, version int) error {
 	const op = "modules.bookcategory.repository.Delete"
 
-	_, err := b.db.ExecContext(ctx, `DELETE FROM book_categories WHERE id = $1`, id)
+	res, err := b.db.ExecContext(ctx, `DELETE FROM book_categories WHERE id = $1 AND version = $2`, id, version)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fmt.Errorf("%s: no book category category found with id = %d", op, id)
+		if postgres.IsForeignKeyViolation(err) {
+			return fmt.Errorf("%s: %w", op, errs.Conflict("book category with id = %d still has books", id))
 		}
 
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, b.missingOrStale(ctx, id))
+	}
+
 	return nil
 }
+
+// missingOrStale tells why a write of the category at some version matched
+// no row.
+func (b *DefaultRepository) missingOrStale(ctx context.Context, id int) error {
+	var exists bool
+
+	err := b.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM book_categories WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return errs.Stale("book category with id = %d has been changed", id)
+	}
+
+	return errs.NotFound("no book category with id = %d", id)
+}
@@ -0,0 +1,37 @@
+package reservation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type Repository interface {
+	CountOverdue(ctx context.Context) (int, error)
+}
+
+type DefaultRepository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+// CountOverdue counts the active reservations whose books weren't returned
+// by the due date.
+func (r *DefaultRepository) CountOverdue(ctx context.Context) (int, error) {
+	const op = "modules.reservation.repository.CountOverdue"
+
+	var n int
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM reservations
+		WHERE status = 'active' AND returned_date IS NULL AND due_date < $1`, time.Now().UTC())
+	if err := row.Scan(&n); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return n, nil
+}
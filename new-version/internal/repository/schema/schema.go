@@ -0,0 +1,31 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type Repository interface {
+	GetVersion(ctx context.Context) (int, error)
+}
+
+type DefaultRepository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+func (s *DefaultRepository) GetVersion(ctx context.Context) (int, error) {
+	const op = "modules.schema.repository.GetVersion"
+
+	var version int
+
+	if err := s.db.QueryRowContext(ctx, `SELECT version FROM schema_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return version, nil
+}
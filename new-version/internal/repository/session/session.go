@@ -0,0 +1,170 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"new-version/internal/contract/session"
+	"new-version/pkg/errs"
+)
+
+type Repository interface {
+	Create(ctx context.Context, s session.Model) error
+	GetById(ctx context.Context, id uuid.UUID) (session.Model, error)
+	GetActiveByUser(ctx context.Context, userId uuid.UUID) ([]session.Model, error)
+	Revoke(ctx context.Context, id uuid.UUID, userId uuid.UUID) error
+	RevokeAllByUser(ctx context.Context, userId uuid.UUID) error
+	Touch(ctx context.Context, id uuid.UUID, olderThan time.Duration) error
+}
+
+type DefaultRepository struct {
+	db *sql.DB
+}
+
+func New(db *sql.DB) *DefaultRepository {
+	return &DefaultRepository{db: db}
+}
+
+const columns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at`
+
+func scan(row interface{ Scan(dest ...any) error }) (session.Model, error) {
+	var (
+		s         session.Model
+		revokedAt sql.NullTime
+	)
+
+	err := row.Scan(&s.Id, &s.UserId, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &revokedAt)
+	if err != nil {
+		return session.Model{}, err
+	}
+
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+
+	return s, nil
+}
+
+func (s *DefaultRepository) Create(ctx context.Context, sess session.Model) error {
+	const op = "modules.session.repository.Create"
+
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		sess.Id, sess.UserId, sess.UserAgent, sess.IP, sess.CreatedAt, sess.LastSeenAt, sess.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultRepository) GetById(ctx context.Context, id uuid.UUID) (session.Model, error) {
+	const op = "modules.session.repository.GetById"
+
+	sess, err := scan(s.db.QueryRowContext(ctx, `SELECT `+columns+` FROM sessions WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return session.Model{}, fmt.Errorf("%s: %w", op, errs.NotFound("no session with id = %s", id))
+		}
+
+		return session.Model{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sess, nil
+}
+
+func (s *DefaultRepository) GetActiveByUser(ctx context.Context, userId uuid.UUID) ([]session.Model, error) {
+	const op = "modules.session.repository.GetActiveByUser"
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+columns+` FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_seen_at DESC`,
+		userId, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var sessions []session.Model
+
+	for rows.Next() {
+		sess, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		sessions = append(sessions, sess)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke marks a session of userId as revoked. Sessions of other users are
+// reported as missing.
+func (s *DefaultRepository) Revoke(ctx context.Context, id uuid.UUID, userId uuid.UUID) error {
+	const op = "modules.session.repository.Revoke"
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		time.Now().UTC(), id, userId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.NotFound("no active session with id = %s", id))
+	}
+
+	return nil
+}
+
+func (s *DefaultRepository) RevokeAllByUser(ctx context.Context, userId uuid.UUID) error {
+	const op = "modules.session.repository.RevokeAllByUser"
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = $1 WHERE user_id = $2 AND revoked_at IS NULL`,
+		time.Now().UTC(), userId,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Touch updates last_seen_at unless it was updated within olderThan, so
+// that busy sessions don't cost a write per request.
+func (s *DefaultRepository) Touch(ctx context.Context, id uuid.UUID, olderThan time.Duration) error {
+	const op = "modules.session.repository.Touch"
+
+	now := time.Now().UTC()
+
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE sessions SET last_seen_at = $1 WHERE id = $2 AND last_seen_at < $3`,
+		now, id, now.Add(-olderThan),
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
@@ -7,10 +7,10 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
-	"modernc.org/sqlite"
-	sqlite3 "modernc.org/sqlite/lib"
 
 	"new-version/internal/contract/user"
+	"new-version/internal/storage/postgres"
+	"new-version/pkg/errs"
 )
 
 type Repository interface {
@@ -20,6 +20,8 @@ type Repository interface {
 	GetInfoByEmail(ctx context.Context, email string) (user.InfoResponse, error)
 	GetPasswordByEmail(ctx context.Context, email string) (string, error)
 	Create(ctx context.Context, userReq user.Request) error
+	SyncExternal(ctx context.Context, email string, accessLevel int) error
+	UpdatePasswordByEmail(ctx context.Context, email string, passHash string) error
 }
 
 type DefaultRepository struct {
@@ -41,7 +43,7 @@ func (u *DefaultRepository) GetById(ctx context.Context, id uuid.UUID) (user.Res
 	row := u.db.QueryRowContext(ctx, `SELECT id, email FROM users WHERE id = $1`, id)
 	if err := row.Scan(&resp.Id, &resp.Email); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user.Response{}, fmt.Errorf("%s: user with this id does not exist", op)
+			return user.Response{}, fmt.Errorf("%s: %w", op, errs.NotFound("user with this id does not exist"))
 		}
 
 		return user.Response{}, fmt.Errorf("%s: %w", op, err)
@@ -50,7 +52,6 @@ func (u *DefaultRepository) GetById(ctx context.Context, id uuid.UUID) (user.Res
 	return resp, nil
 }
 
-// Not used
 func (u *DefaultRepository) GetInfoById(ctx context.Context, id uuid.UUID) (user.InfoResponse, error) {
 	const op = "modules.user.repository.GetInfoById"
 
@@ -60,7 +61,7 @@ func (u *DefaultRepository) GetInfoById(ctx context.Context, id uuid.UUID) (user
 		`SELECT id, email, joined_at, access_level FROM users WHERE id = $1`, id)
 	if err := row.Scan(&resp.Id, &resp.Email, &resp.JoinedAt, &resp.AccessLevel); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user.InfoResponse{}, fmt.Errorf("%s: user with this id does not exist", op)
+			return user.InfoResponse{}, fmt.Errorf("%s: %w", op, errs.NotFound("user with this id does not exist"))
 		}
 
 		return user.InfoResponse{}, fmt.Errorf("%s: %w", op, err)
@@ -77,7 +78,7 @@ func (u *DefaultRepository) GetByEmail(ctx context.Context, email string) (user.
 	row := u.db.QueryRowContext(ctx, `SELECT id, email, pass_hash FROM users WHERE email = $1`, email)
 	if err := row.Scan(&resp.Id, &resp.Email); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user.Response{}, fmt.Errorf("%s: user with this email does not exist", op)
+			return user.Response{}, fmt.Errorf("%s: %w", op, errs.NotFound("user with this email does not exist"))
 		}
 
 		return user.Response{}, fmt.Errorf("%s: %w", op, err)
@@ -97,7 +98,7 @@ func (u *DefaultRepository) GetInfoByEmail(ctx context.Context, email string) (u
 		&resp.Id, &resp.Email, &resp.JoinedAt, &resp.AccessLevel,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return user.InfoResponse{}, fmt.Errorf("%s: user with this email does not exist", op)
+			return user.InfoResponse{}, fmt.Errorf("%s: %w", op, errs.NotFound("user with this email does not exist"))
 		}
 
 		return user.InfoResponse{}, fmt.Errorf("%s: %w", op, err)
@@ -115,7 +116,7 @@ func (u *DefaultRepository) GetPasswordByEmail(ctx context.Context, email string
 		`SELECT pass_hash FROM users WHERE email = $1`, email)
 	if err := row.Scan(&pass); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return "", fmt.Errorf("%s: user with this email does not exist", op)
+			return "", fmt.Errorf("%s: %w", op, errs.NotFound("user with this email does not exist"))
 		}
 
 		return "", fmt.Errorf("%s: %w", op, err)
@@ -130,8 +131,8 @@ func (u *DefaultRepository) Create(ctx context.Context, userReq user.Request) er
 	_, err := u.db.ExecContext(ctx,
 		`INSERT INTO users(id, email, pass_hash) VALUES($1, $2, $3)`, uuid.New(), userReq.Email, userReq.Password)
 	if err != nil {
-		if sqliteErr, ok := err.(*sqlite.Error); ok && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
-			return fmt.Errorf("%s: user with this email '%s' already exists", op, userReq.Email)
+		if postgres.IsUniqueViolation(err) {
+			return fmt.Errorf("%s: %w", op, errs.Conflict("user with this email '%s' already exists", userReq.Email))
 		}
 
 		return fmt.Errorf("%s: %w", op, err)
@@ -139,3 +140,37 @@ func (u *DefaultRepository) Create(ctx context.Context, userReq user.Request) er
 
 	return nil
 }
+
+// SyncExternal creates or updates a local record for a user authenticated by an
+// external directory. Such users get an unusable password hash, so they can't
+// log in through the local authenticator. The access level of an existing
+// local account is left as is.
+func (u *DefaultRepository) SyncExternal(ctx context.Context, email string, accessLevel int) error {
+	const op = "modules.user.repository.SyncExternal"
+
+	_, err := u.db.ExecContext(ctx,
+		`INSERT INTO users(id, email, pass_hash, access_level) VALUES($1, $2, $3, $4)
+		ON CONFLICT (email) DO UPDATE SET access_level = EXCLUDED.access_level
+		WHERE users.pass_hash = '!'`,
+		uuid.New(), email, "!", accessLevel)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (u *DefaultRepository) UpdatePasswordByEmail(ctx context.Context, email string, passHash string) error {
+	const op = "modules.user.repository.UpdatePasswordByEmail"
+
+	res, err := u.db.ExecContext(ctx, `UPDATE users SET pass_hash = $1 WHERE email = $2`, passHash, email)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%s: %w", op, errs.NotFound("user with this email does not exist"))
+	}
+
+	return nil
+}
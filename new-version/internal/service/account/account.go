@@ -0,0 +1,239 @@
+package account
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"new-version/internal/config"
+	accountDto "new-version/internal/contract/account"
+	auditDto "new-version/internal/contract/audit"
+	accountRepo "new-version/internal/repository/account"
+	userRepo "new-version/internal/repository/user"
+	auditSvc "new-version/internal/service/audit"
+	"new-version/internal/tracing"
+	"new-version/pkg/errs"
+)
+
+// purgeActor is recorded as the actor of purges done by RunPurge.
+const purgeActor = "system"
+
+var ErrOutstandingLoans = errs.Conflict("return all borrowed books before deleting the account")
+
+type Service interface {
+	Export(ctx context.Context, userId uuid.UUID) (accountDto.Export, error)
+	RequestDeletion(ctx context.Context, userId uuid.UUID) (accountDto.Deletion, error)
+	CancelDeletion(ctx context.Context, userId uuid.UUID) error
+	PurgeDue(ctx context.Context) (int, error)
+}
+
+type DefaultService struct {
+	log   *slog.Logger
+	repo  accountRepo.Repository
+	users userRepo.Repository
+	audit auditSvc.Service
+	cfg   *config.Privacy
+}
+
+func New(
+	log *slog.Logger,
+	repo accountRepo.Repository,
+	users userRepo.Repository,
+	audit auditSvc.Service,
+	cfg *config.Privacy,
+) *DefaultService {
+	return &DefaultService{
+		log:   log,
+		repo:  repo,
+		users: users,
+		audit: audit,
+		cfg:   cfg,
+	}
+}
+
+func (a *DefaultService) Export(ctx context.Context, userId uuid.UUID) (_ accountDto.Export, err error) {
+	const op = "service.account.Export"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	export, err := a.export(ctx, userId)
+
+	a.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionAccountExport,
+		Target:  userId.String(),
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return accountDto.Export{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return export, nil
+}
+
+func (a *DefaultService) export(ctx context.Context, userId uuid.UUID) (accountDto.Export, error) {
+	var (
+		export = accountDto.Export{GeneratedAt: time.Now().UTC()}
+		err    error
+	)
+
+	if export.Profile, err = a.users.GetInfoById(ctx, userId); err != nil {
+		return accountDto.Export{}, err
+	}
+
+	if export.Reservations, err = a.repo.GetReservations(ctx, userId); err != nil {
+		return accountDto.Export{}, err
+	}
+
+	if export.Reviews, err = a.repo.GetReviews(ctx, userId); err != nil {
+		return accountDto.Export{}, err
+	}
+
+	if export.Notifications, err = a.repo.GetNotifications(ctx, userId); err != nil {
+		return accountDto.Export{}, err
+	}
+
+	return export, nil
+}
+
+// RequestDeletion schedules the account for deletion after the grace period.
+// It returns ErrOutstandingLoans while the user still has books to return.
+func (a *DefaultService) RequestDeletion(ctx context.Context, userId uuid.UUID) (_ accountDto.Deletion, err error) {
+	const op = "service.account.RequestDeletion"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	deletion, err := a.requestDeletion(ctx, userId)
+
+	event := auditDto.Event{
+		Action:  auditSvc.ActionAccountDelete,
+		Target:  userId.String(),
+		Outcome: auditSvc.Outcome(err),
+	}
+	if err != nil {
+		event.Details = err.Error()
+	}
+	a.audit.Record(ctx, event)
+
+	if err != nil {
+		return accountDto.Deletion{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deletion, nil
+}
+
+func (a *DefaultService) requestDeletion(ctx context.Context, userId uuid.UUID) (accountDto.Deletion, error) {
+	loans, err := a.repo.CountOutstandingLoans(ctx, userId)
+	if err != nil {
+		return accountDto.Deletion{}, err
+	}
+
+	if loans > 0 {
+		return accountDto.Deletion{}, ErrOutstandingLoans
+	}
+
+	requestedAt, err := a.repo.RequestDeletion(ctx, userId, time.Now().UTC())
+	if err != nil {
+		return accountDto.Deletion{}, err
+	}
+
+	return accountDto.Deletion{
+		RequestedAt: requestedAt,
+		PurgeAfter:  requestedAt.Add(a.cfg.DeletionGracePeriod),
+	}, nil
+}
+
+func (a *DefaultService) CancelDeletion(ctx context.Context, userId uuid.UUID) (err error) {
+	const op = "service.account.CancelDeletion"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	err = a.repo.CancelDeletion(ctx, userId)
+
+	a.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionAccountRestore,
+		Target:  userId.String(),
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeDue deletes the accounts whose grace period is over and returns how
+// many were deleted. Accounts that borrowed books during the grace period are
+// skipped until the books are returned.
+func (a *DefaultService) PurgeDue(ctx context.Context) (_ int, err error) {
+	const op = "service.account.PurgeDue"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	ids, err := a.repo.GetDueForDeletion(ctx, time.Now().UTC().Add(-a.cfg.DeletionGracePeriod))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ctx = auditSvc.WithMeta(ctx, auditSvc.Meta{Actor: purgeActor})
+	purged := 0
+
+	for _, id := range ids {
+		loans, err := a.repo.CountOutstandingLoans(ctx, id)
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if loans > 0 {
+			a.log.Warn("account deletion postponed", slog.String("op", op), slog.String("user_id", id.String()), slog.Int("loans", loans))
+			continue
+		}
+
+		err = a.repo.Purge(ctx, id)
+
+		a.audit.Record(ctx, auditDto.Event{
+			Action:  auditSvc.ActionAccountPurge,
+			Target:  id.String(),
+			Outcome: auditSvc.Outcome(err),
+		})
+
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", op, err)
+		}
+
+		purged++
+	}
+
+	return purged, nil
+}
+
+// RunPurge calls PurgeDue every cfg.PurgeInterval until ctx is done.
+func (a *DefaultService) RunPurge(ctx context.Context) {
+	const op = "service.account.RunPurge"
+
+	ticker := time.NewTicker(a.cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := a.PurgeDue(ctx)
+			if err != nil {
+				a.log.Error("failed to purge accounts", slog.String("op", op), slog.String("error", err.Error()))
+			}
+			if n > 0 {
+				a.log.Info("purged accounts", slog.String("op", op), slog.Int("count", n))
+			}
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	auditDto "new-version/internal/contract/audit"
+	auditRepo "new-version/internal/repository/audit"
+	"new-version/internal/tracing"
+	hp "new-version/pkg/httphelpers"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+const (
+	ActionLogin              = "user.login"
+	ActionRegister           = "user.register"
+	ActionRoleChange         = "user.role_change"
+	ActionSessionRevoke      = "session.revoke"
+	ActionAccountExport      = "account.export"
+	ActionAccountDelete      = "account.delete"
+	ActionAccountRestore     = "account.restore"
+	ActionAccountPurge       = "account.purge"
+	ActionImpersonationStart = "impersonation.start"
+	ActionImpersonationEnd   = "impersonation.end"
+	ActionCategoryCreate     = "book_category.create"
+	ActionCategoryUpdate     = "book_category.update"
+	ActionCategoryDelete     = "book_category.delete"
+)
+
+// Meta describes who performs the request. Handlers put it into the context
+// so services don't have to pass it around explicitly.
+type Meta struct {
+	Actor        string
+	Impersonator string
+	IP           string
+	UserAgent    string
+}
+
+type metaKey struct{}
+
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, metaKey{}, meta)
+}
+
+func WithRequest(ctx context.Context, r *http.Request, actor string) context.Context {
+	return WithMeta(ctx, Meta{
+		Actor:     actor,
+		IP:        hp.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+}
+
+func MetaFromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(metaKey{}).(Meta)
+	return meta
+}
+
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+
+	return OutcomeSuccess
+}
+
+type Service interface {
+	Record(ctx context.Context, event auditDto.Event)
+	List(ctx context.Context, filter auditDto.Filter) ([]auditDto.Event, error)
+}
+
+type DefaultService struct {
+	log  *slog.Logger
+	repo auditRepo.Repository
+}
+
+func New(log *slog.Logger, repo auditRepo.Repository) *DefaultService {
+	return &DefaultService{log: log, repo: repo}
+}
+
+// Record stores event, filling the actor, IP and user agent from the request
+// meta when they're not set. A failure to write is logged and never fails the
+// audited operation.
+func (a *DefaultService) Record(ctx context.Context, event auditDto.Event) {
+	const op = "service.audit.Record"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer span.End()
+
+	meta := MetaFromContext(ctx)
+
+	if event.Actor == "" {
+		event.Actor = meta.Actor
+	}
+	if event.Impersonator == "" {
+		event.Impersonator = meta.Impersonator
+	}
+	if event.IP == "" {
+		event.IP = meta.IP
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = meta.UserAgent
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	// the audited operation may have exhausted the request deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 3*time.Second)
+	defer cancel()
+
+	if err := a.repo.Create(ctx, event); err != nil {
+		tracing.Fail(span, err)
+		a.log.ErrorContext(ctx, "failed to write audit event",
+			slog.String("op", op),
+			slog.String("action", event.Action),
+			slog.String("actor", event.Actor),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+func (a *DefaultService) List(ctx context.Context, filter auditDto.Filter) (_ []auditDto.Event, err error) {
+	const op = "service.audit.List"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	events, err := a.repo.GetList(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
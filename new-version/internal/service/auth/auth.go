@@ -5,68 +5,124 @@ import (
 	"log/slog"
 	"new-version/internal/config"
 	"new-version/internal/contract/user"
+	"new-version/pkg/hasher"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
 type Service interface {
 	HashPassword(pass string) (string, error)
 	ComparePassword(hashPass string, pass string) (bool, error)
-	GenerateJwtToken(userInfo user.Model) (string, error)
+	NeedsRehash(hashPass string) bool
+	GenerateJwtToken(userInfo user.Model, sessionId uuid.UUID) (string, error)
+	GenerateImpersonationToken(target user.Model, impersonator user.Model, sessionId uuid.UUID) (string, error)
 }
 
 type JwtService struct {
-	log *slog.Logger
-	cfg *config.Security
+	log    *slog.Logger
+	cfg    *config.Security
+	hasher hasher.Hasher
 }
 
-func New(log *slog.Logger, cfg *config.Security) *JwtService {
-	return &JwtService{log: log, cfg: cfg}
+func New(log *slog.Logger, cfg *config.Security, hasher hasher.Hasher) *JwtService {
+	return &JwtService{log: log, cfg: cfg, hasher: hasher}
+}
+
+func NewHasher(cfg *config.Hashing) (hasher.Hasher, error) {
+	const op = "service.auth.NewHasher"
+
+	h, err := hasher.New(hasher.Options{
+		Algorithm:  cfg.Algorithm,
+		BcryptCost: cfg.BcryptCost,
+		Argon2: hasher.Argon2Params{
+			Memory:      cfg.Argon2Memory,
+			Iterations:  cfg.Argon2Iterations,
+			Parallelism: cfg.Argon2Parallelism,
+			SaltLength:  cfg.Argon2SaltLength,
+			KeyLength:   cfg.Argon2KeyLength,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return h, nil
 }
 
 func (j *JwtService) HashPassword(pass string) (string, error) {
-	const op = "modules.user.service.HashPass"
+	const op = "service.auth.HashPassword"
 
-	h, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.DefaultCost)
+	h, err := j.hasher.Hash(pass)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return string(h), nil
+	return h, nil
 }
 
 func (j *JwtService) ComparePassword(hashPass string, pass string) (bool, error) {
 	const op = "service.auth.ComparePassword"
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashPass), []byte(pass)); err != nil {
-		if err == bcrypt.ErrMismatchedHashAndPassword {
-			return false, fmt.Errorf("%s: wrong password", op)
-		}
-
+	ok, err := j.hasher.Verify(hashPass, pass)
+	if err != nil {
 		return false, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return true, nil
+	return ok, nil
+}
+
+// NeedsRehash reports whether hashPass was produced by another algorithm or
+// with parameters that differ from the configured ones.
+func (j *JwtService) NeedsRehash(hashPass string) bool {
+	return j.hasher.NeedsRehash(hashPass)
 }
 
-func (j *JwtService) GenerateJwtToken(userInfo user.Model) (string, error) {
+func (j *JwtService) GenerateJwtToken(userInfo user.Model, sessionId uuid.UUID) (string, error) {
 	const op = "service.auth.GenJwtToken"
 
 	claims := jwt.MapClaims{
 		"sub":        userInfo.Email,
+		"uid":        userInfo.Id.String(),
+		"sid":        sessionId.String(),
 		"user_level": userInfo.AccessLevel,
 		"exp":        time.Now().Add(j.cfg.AccessTokenExpire).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedToken, err := j.sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	return signedToken, nil
+}
 
-	signedToken, err := token.SignedString([]byte(j.cfg.JwtSecret))
+// GenerateImpersonationToken issues a token that acts as target and carries
+// the impersonating admin in the "imp_sub" and "imp_uid" claims.
+func (j *JwtService) GenerateImpersonationToken(target user.Model, impersonator user.Model, sessionId uuid.UUID) (string, error) {
+	const op = "service.auth.GenImpersonationToken"
 
+	claims := jwt.MapClaims{
+		"sub":        target.Email,
+		"uid":        target.Id.String(),
+		"sid":        sessionId.String(),
+		"user_level": target.AccessLevel,
+		"imp_sub":    impersonator.Email,
+		"imp_uid":    impersonator.Id.String(),
+		"exp":        time.Now().Add(j.cfg.ImpersonationExpire).Unix(),
+	}
+
+	signedToken, err := j.sign(claims)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	return signedToken, nil
 }
+
+func (j *JwtService) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+
+	return token.SignedString([]byte(j.cfg.JwtSecret))
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	userRepo "new-version/internal/repository/user"
+	"new-version/internal/tracing"
+	"new-version/pkg/errs"
+	"new-version/pkg/hasher"
+)
+
+const (
+	SourceLocal = "local"
+	SourceLDAP  = "ldap"
+)
+
+// Both errors read the same to clients, so that logins can't be used to
+// find out which emails are registered.
+var (
+	ErrInvalidCredentials = errs.Unauthorized("invalid credentials")
+	ErrUnknownUser        = errs.Unauthorized("invalid credentials")
+)
+
+type Identity struct {
+	Id          uuid.UUID
+	Email       string
+	AccessLevel int
+	Source      string
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, email string, pass string) (Identity, error)
+}
+
+// LocalAuthenticator checks credentials against the password hashes stored
+// in the users table, upgrading hashes made with outdated parameters.
+type LocalAuthenticator struct {
+	log  *slog.Logger
+	repo userRepo.Repository
+	auth Service
+}
+
+func NewLocal(log *slog.Logger, repo userRepo.Repository, auth Service) *LocalAuthenticator {
+	return &LocalAuthenticator{log: log, repo: repo, auth: auth}
+}
+
+func (l *LocalAuthenticator) Authenticate(ctx context.Context, email string, pass string) (_ Identity, err error) {
+	const op = "service.auth.LocalAuthenticator.Authenticate"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	hashPass, err := l.repo.GetPasswordByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, errs.ErrNotFound) {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrUnknownUser)
+		}
+
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	valid, err := l.auth.ComparePassword(hashPass, pass)
+	if err != nil {
+		// users provisioned from a directory have no local password
+		if errors.Is(err, hasher.ErrUnknownFormat) {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !valid {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	if l.auth.NeedsRehash(hashPass) {
+		l.rehash(ctx, email, pass)
+	}
+
+	userInfo, err := l.repo.GetInfoByEmail(ctx, email)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Identity{
+		Id:          userInfo.Id,
+		Email:       userInfo.Email,
+		AccessLevel: userInfo.AccessLevel,
+		Source:      SourceLocal,
+	}, nil
+}
+
+// rehash stores a fresh hash of the verified password. Failing to do so
+// doesn't fail the login, the upgrade is retried on the next one.
+func (l *LocalAuthenticator) rehash(ctx context.Context, email string, pass string) {
+	const op = "service.auth.LocalAuthenticator.rehash"
+
+	hashPass, err := l.auth.HashPassword(pass)
+	if err == nil {
+		err = l.repo.UpdatePasswordByEmail(ctx, email, hashPass)
+	}
+
+	if err != nil {
+		l.log.WarnContext(ctx, "failed to rehash password", slog.String("op", op), slog.String("error", err.Error()))
+	}
+}
+
+// ChainAuthenticator tries authenticators in order. A rejected password stops
+// the chain, any other failure (unknown user, unreachable directory) falls
+// through to the next authenticator.
+type ChainAuthenticator struct {
+	log            *slog.Logger
+	authenticators []Authenticator
+}
+
+func NewChain(log *slog.Logger, authenticators ...Authenticator) *ChainAuthenticator {
+	return &ChainAuthenticator{log: log, authenticators: authenticators}
+}
+
+func (c *ChainAuthenticator) Authenticate(ctx context.Context, email string, pass string) (_ Identity, err error) {
+	const op = "service.auth.ChainAuthenticator.Authenticate"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	lastErr := ErrUnknownUser
+
+	for _, a := range c.authenticators {
+		identity, err := a.Authenticate(ctx, email, pass)
+		if err == nil {
+			return identity, nil
+		}
+
+		if errors.Is(err, ErrInvalidCredentials) {
+			return Identity{}, fmt.Errorf("%s: %w", op, err)
+		}
+
+		if !errors.Is(err, ErrUnknownUser) {
+			c.log.WarnContext(ctx, "authenticator failed, trying next one", slog.String("op", op), slog.String("error", err.Error()))
+		}
+
+		lastErr = err
+	}
+
+	return Identity{}, fmt.Errorf("%s: %w", op, lastErr)
+}
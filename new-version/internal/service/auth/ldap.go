@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+
+	"new-version/internal/config"
+	"new-version/internal/tracing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConn is the subset of *ldap.Conn used by LDAPAuthenticator.
+type LDAPConn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+type LDAPDialer func(ctx context.Context) (LDAPConn, error)
+
+// LDAPAuthenticator binds with a service account, looks the user up by
+// email and then binds as the found entry to verify the password.
+type LDAPAuthenticator struct {
+	log  *slog.Logger
+	cfg  *config.LDAP
+	dial LDAPDialer
+}
+
+func NewLDAP(log *slog.Logger, cfg *config.LDAP, dial LDAPDialer) *LDAPAuthenticator {
+	if dial == nil {
+		dial = DialLDAP(cfg)
+	}
+
+	return &LDAPAuthenticator{log: log, cfg: cfg, dial: dial}
+}
+
+func DialLDAP(cfg *config.LDAP) LDAPDialer {
+	return func(ctx context.Context) (LDAPConn, error) {
+		const op = "service.auth.DialLDAP"
+
+		conn, err := ldap.DialURL(cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: cfg.Timeout}))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		conn.SetTimeout(cfg.Timeout)
+
+		if cfg.StartTLS {
+			u, err := url.Parse(cfg.URL)
+			if err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+
+			if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("%s: %w", op, err)
+			}
+		}
+
+		return conn, nil
+	}
+}
+
+func (l *LDAPAuthenticator) Authenticate(ctx context.Context, email string, pass string) (_ Identity, err error) {
+	const op = "service.auth.LDAPAuthenticator.Authenticate"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	// an empty password turns the user bind into an unauthenticated bind,
+	// which most servers accept
+	if pass == "" {
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+	}
+
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer conn.Close()
+
+	if l.cfg.BindDN != "" {
+		if err := conn.Bind(l.cfg.BindDN, l.cfg.BindPassword); err != nil {
+			return Identity{}, fmt.Errorf("%s: service bind: %w", op, err)
+		}
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		l.cfg.BaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		2,
+		int(l.cfg.Timeout.Seconds()),
+		false,
+		fmt.Sprintf(l.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{"dn", l.cfg.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrUnknownUser)
+		}
+
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	switch len(res.Entries) {
+	case 0:
+		return Identity{}, fmt.Errorf("%s: %w", op, ErrUnknownUser)
+	case 1:
+	default:
+		return Identity{}, fmt.Errorf("%s: more than one entry matches %s", op, email)
+	}
+
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, pass); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return Identity{}, fmt.Errorf("%s: %w", op, ErrInvalidCredentials)
+		}
+
+		return Identity{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return Identity{
+		Email:       email,
+		AccessLevel: l.accessLevel(entry.GetAttributeValues(l.cfg.GroupAttribute)),
+		Source:      SourceLDAP,
+	}, nil
+}
+
+// accessLevel returns the highest level mapped to any of the groups,
+// or the configured default when none of them is mapped.
+func (l *LDAPAuthenticator) accessLevel(groups []string) int {
+	level := l.cfg.DefaultLevel
+
+	for _, g := range groups {
+		for dn, lvl := range l.cfg.GroupRoles {
+			if strings.EqualFold(dn, g) && lvl > level {
+				level = lvl
+			}
+		}
+	}
+
+	return level
+}
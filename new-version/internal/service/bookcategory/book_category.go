@@ -0,0 +1,145 @@
+package bookcategory
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	auditDto "new-version/internal/contract/audit"
+	"new-version/internal/contract/bookcategory"
+	bookCatRepo "new-version/internal/repository/bookcategory"
+	auditSvc "new-version/internal/service/audit"
+	"new-version/internal/tracing"
+)
+
+type Service interface {
+	GetById(ctx context.Context, id int) (bookcategory.Response, error)
+	Create(ctx context.Context, bookCat bookcategory.Request) (int, error)
+	DeleteById(ctx context.Context, id int, version int) error
+	UpdateById(ctx context.Context, bookCat bookcategory.Request, id int, version int) error
+	GetByTitle(ctx context.Context, title string) (bookcategory.Response, error)
+	GetList(ctx context.Context) ([]bookcategory.Response, error)
+}
+
+type DefaultService struct {
+	log   *slog.Logger
+	repo  bookCatRepo.Repository
+	audit auditSvc.Service
+}
+
+func New(log *slog.Logger, repo bookCatRepo.Repository, audit auditSvc.Service) *DefaultService {
+	return &DefaultService{
+		log:   log,
+		repo:  repo,
+		audit: audit,
+	}
+}
+
+func (b *DefaultService) GetById(ctx context.Context, id int) (_ bookcategory.Response, err error) {
+	const op = "service.bookcategory.GetById"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	bookCat, err := b.repo.GetById(ctx, id)
+	if err != nil {
+		return bookcategory.Response{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bookCat, nil
+}
+
+func (b *DefaultService) GetByTitle(ctx context.Context, title string) (_ bookcategory.Response, err error) {
+	const op = "service.bookcategory.GetByTitle"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	bookCat, err := b.repo.GetByTitle(ctx, title)
+	if err != nil {
+		return bookcategory.Response{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bookCat, nil
+}
+
+func (b *DefaultService) GetList(ctx context.Context) (_ []bookcategory.Response, err error) {
+	const op = "service.bookcategory.GetList"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	bookCats, err := b.repo.GetList(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return bookCats, nil
+}
+
+func (b *DefaultService) Create(ctx context.Context, bookCat bookcategory.Request) (_ int, err error) {
+	const op = "service.bookcategory.Create"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	id, err := b.repo.Create(ctx, bookCat)
+
+	b.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionCategoryCreate,
+		Target:  bookCat.Title,
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// UpdateById replaces the category if it's still at version, which the
+// caller read before. Otherwise it fails with errs.ErrStale.
+func (b *DefaultService) UpdateById(ctx context.Context, bookCat bookcategory.Request, id int, version int) (err error) {
+	const op = "service.bookcategory.UpdateById"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	err = b.repo.UpdateById(ctx, bookCat, id, version)
+
+	b.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionCategoryUpdate,
+		Target:  strconv.Itoa(id),
+		Outcome: auditSvc.Outcome(err),
+		Details: "title: " + bookCat.Title,
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (b *DefaultService) DeleteById(ctx context.Context, id int, version int) (err error) {
+	const op = "service.bookcategory.DeleteById"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	err = b.repo.DeleteById(ctx, id, version)
+
+	b.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionCategoryDelete,
+		Target:  strconv.Itoa(id),
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
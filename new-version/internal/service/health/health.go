@@ -0,0 +1,144 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"new-version/internal/buildinfo"
+	healthDto "new-version/internal/contract/health"
+	schemaRepo "new-version/internal/repository/schema"
+)
+
+// checkTimeout bounds each readiness check, probes usually time out after
+// a few seconds.
+const checkTimeout = 2 * time.Second
+
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type Service interface {
+	Ready(ctx context.Context) healthDto.Report
+	Version(ctx context.Context) healthDto.Version
+}
+
+type DefaultService struct {
+	log           *slog.Logger
+	db            Pinger
+	schema        schemaRepo.Repository
+	schemaVersion int
+	storagePath   string
+}
+
+// New returns a service checking db, that the schema is at schemaVersion and
+// that files can be written to storagePath.
+func New(log *slog.Logger, db Pinger, schema schemaRepo.Repository, schemaVersion int, storagePath string) *DefaultService {
+	return &DefaultService{
+		log:           log,
+		db:            db,
+		schema:        schema,
+		schemaVersion: schemaVersion,
+		storagePath:   storagePath,
+	}
+}
+
+// Ready runs all checks. Their errors are logged, the report only says
+// which check failed so that it can be served publicly.
+func (h *DefaultService) Ready(ctx context.Context) healthDto.Report {
+	checks := []struct {
+		name  string
+		check func(ctx context.Context) (string, error)
+	}{
+		{"database", h.checkDatabase},
+		{"schema", h.checkSchema},
+		{"storage", h.checkStorage},
+	}
+
+	report := healthDto.Report{Status: healthDto.StatusOK}
+
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+		msg, err := c.check(ctx)
+		cancel()
+
+		result := healthDto.Check{Name: c.name, Status: healthDto.StatusOK}
+		if err != nil {
+			h.log.ErrorContext(ctx, "readiness check failed", slog.String("check", c.name), slog.String("error", err.Error()))
+
+			result.Status = healthDto.StatusUnavailable
+			result.Error = msg
+			report.Status = healthDto.StatusUnavailable
+		}
+
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+func (h *DefaultService) checkDatabase(ctx context.Context) (string, error) {
+	if err := h.db.PingContext(ctx); err != nil {
+		return "database is unreachable", err
+	}
+
+	return "", nil
+}
+
+func (h *DefaultService) checkSchema(ctx context.Context) (string, error) {
+	version, err := h.schema.GetVersion(ctx)
+	if err != nil {
+		return "schema version is unknown", err
+	}
+
+	if version != h.schemaVersion {
+		msg := fmt.Sprintf("schema version is %d, expected %d", version, h.schemaVersion)
+		return msg, fmt.Errorf("%s", msg)
+	}
+
+	return "", nil
+}
+
+func (h *DefaultService) checkStorage(context.Context) (string, error) {
+	f, err := os.CreateTemp(h.storagePath, ".readyz-*")
+	if err != nil {
+		return "storage is not writable", err
+	}
+
+	f.Close()
+
+	if err := os.Remove(f.Name()); err != nil {
+		return "storage is not writable", err
+	}
+
+	return "", nil
+}
+
+// Version reports the build and the schema version of the database, 0 when
+// it can't be read.
+func (h *DefaultService) Version(ctx context.Context) healthDto.Version {
+	const op = "service.health.Version"
+
+	info := buildinfo.Get()
+
+	version := healthDto.Version{
+		Commit:    info.Commit,
+		BuildTime: info.Time,
+		Modified:  info.Modified,
+		GoVersion: info.GoVersion,
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	schemaVersion, err := h.schema.GetVersion(ctx)
+	if err != nil {
+		h.log.WarnContext(ctx, "failed to read schema version", slog.String("op", op), slog.String("error", err.Error()))
+	}
+
+	version.SchemaVersion = schemaVersion
+
+	return version
+}
@@ -0,0 +1,158 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"new-version/internal/config"
+	auditDto "new-version/internal/contract/audit"
+	userDto "new-version/internal/contract/user"
+	userRepo "new-version/internal/repository/user"
+	auditSvc "new-version/internal/service/audit"
+	authSvc "new-version/internal/service/auth"
+	sessionSvc "new-version/internal/service/session"
+	"new-version/internal/tracing"
+	"new-version/pkg/errs"
+	hp "new-version/pkg/httphelpers"
+)
+
+var (
+	ErrSelf       = errs.Forbidden("can't impersonate yourself")
+	ErrPrivileged = errs.Forbidden("can't impersonate an admin")
+)
+
+// Admin is the admin starting an impersonation.
+type Admin struct {
+	Id    uuid.UUID
+	Email string
+}
+
+type Started struct {
+	Token     string    `json:"token"`
+	SessionId uuid.UUID `json:"session_id"`
+	UserId    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Service interface {
+	Start(ctx context.Context, admin Admin, userId uuid.UUID) (Started, error)
+	End(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) error
+}
+
+type DefaultService struct {
+	log      *slog.Logger
+	users    userRepo.Repository
+	auth     authSvc.Service
+	sessions sessionSvc.Service
+	audit    auditSvc.Service
+	cfg      *config.Security
+}
+
+func New(
+	log *slog.Logger,
+	users userRepo.Repository,
+	auth authSvc.Service,
+	sessions sessionSvc.Service,
+	audit auditSvc.Service,
+	cfg *config.Security,
+) *DefaultService {
+	return &DefaultService{
+		log:      log,
+		users:    users,
+		auth:     auth,
+		sessions: sessions,
+		audit:    audit,
+		cfg:      cfg,
+	}
+}
+
+// Start opens a session of userId on behalf of admin and returns a token for
+// it. Admin accounts can't be impersonated.
+func (i *DefaultService) Start(ctx context.Context, admin Admin, userId uuid.UUID) (_ Started, err error) {
+	const op = "service.impersonation.Start"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	started, err := i.start(ctx, admin, userId)
+
+	event := auditDto.Event{
+		Action:  auditSvc.ActionImpersonationStart,
+		Target:  userId.String(),
+		Outcome: auditSvc.Outcome(err),
+	}
+	if err != nil {
+		event.Details = err.Error()
+	}
+	i.audit.Record(ctx, event)
+
+	if err != nil {
+		return Started{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return started, nil
+}
+
+func (i *DefaultService) start(ctx context.Context, admin Admin, userId uuid.UUID) (Started, error) {
+	if admin.Id == userId {
+		return Started{}, ErrSelf
+	}
+
+	target, err := i.users.GetInfoById(ctx, userId)
+	if err != nil {
+		return Started{}, err
+	}
+
+	if hp.AccessLevel(target.AccessLevel) >= hp.ADMIN_LVL {
+		return Started{}, ErrPrivileged
+	}
+
+	sessionId, err := i.sessions.Create(ctx, target.Id, i.cfg.ImpersonationExpire)
+	if err != nil {
+		return Started{}, err
+	}
+
+	token, err := i.auth.GenerateImpersonationToken(
+		userDto.Model{Id: target.Id, Email: target.Email, AccessLevel: target.AccessLevel},
+		userDto.Model{Id: admin.Id, Email: admin.Email},
+		sessionId,
+	)
+	if err != nil {
+		return Started{}, err
+	}
+
+	return Started{
+		Token:     token,
+		SessionId: sessionId,
+		UserId:    target.Id,
+		Email:     target.Email,
+		ExpiresAt: time.Now().UTC().Add(i.cfg.ImpersonationExpire),
+	}, nil
+}
+
+// End revokes the impersonation session, the token stops working right away.
+func (i *DefaultService) End(ctx context.Context, userId uuid.UUID, sessionId uuid.UUID) (err error) {
+	const op = "service.impersonation.End"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	err = i.sessions.Revoke(ctx, userId, sessionId)
+
+	i.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionImpersonationEnd,
+		Target:  userId.String(),
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
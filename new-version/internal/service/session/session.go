@@ -0,0 +1,157 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	auditDto "new-version/internal/contract/audit"
+	sessionDto "new-version/internal/contract/session"
+	sessionRepo "new-version/internal/repository/session"
+	auditSvc "new-version/internal/service/audit"
+	"new-version/internal/tracing"
+	"new-version/pkg/errs"
+)
+
+// touchInterval limits how often last_seen_at is written for a session.
+const touchInterval = time.Minute
+
+var ErrInactive = errs.Unauthorized("session is revoked or expired")
+
+type Service interface {
+	Create(ctx context.Context, userId uuid.UUID, ttl time.Duration) (uuid.UUID, error)
+	List(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) ([]sessionDto.Response, error)
+	Revoke(ctx context.Context, userId uuid.UUID, id uuid.UUID) error
+	RevokeAll(ctx context.Context, userId uuid.UUID) error
+	Validate(ctx context.Context, id uuid.UUID) error
+}
+
+type DefaultService struct {
+	log   *slog.Logger
+	repo  sessionRepo.Repository
+	audit auditSvc.Service
+}
+
+func New(log *slog.Logger, repo sessionRepo.Repository, audit auditSvc.Service) *DefaultService {
+	return &DefaultService{
+		log:   log,
+		repo:  repo,
+		audit: audit,
+	}
+}
+
+// Create opens a session for userId, taking the device and IP from the
+// request meta in ctx.
+func (s *DefaultService) Create(ctx context.Context, userId uuid.UUID, ttl time.Duration) (_ uuid.UUID, err error) {
+	const op = "service.session.Create"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	meta := auditSvc.MetaFromContext(ctx)
+	now := time.Now().UTC()
+
+	sess := sessionDto.Model{
+		Id:         uuid.New(),
+		UserId:     userId,
+		UserAgent:  meta.UserAgent,
+		IP:         meta.IP,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	if err := s.repo.Create(ctx, sess); err != nil {
+		return uuid.Nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return sess.Id, nil
+}
+
+func (s *DefaultService) List(ctx context.Context, userId uuid.UUID, currentId uuid.UUID) (_ []sessionDto.Response, err error) {
+	const op = "service.session.List"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	sessions, err := s.repo.GetActiveByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	resp := make([]sessionDto.Response, 0, len(sessions))
+	for _, sess := range sessions {
+		resp = append(resp, sessionDto.Response{
+			Id:         sess.Id,
+			UserAgent:  sess.UserAgent,
+			IP:         sess.IP,
+			CreatedAt:  sess.CreatedAt,
+			LastSeenAt: sess.LastSeenAt,
+			ExpiresAt:  sess.ExpiresAt,
+			Current:    sess.Id == currentId,
+		})
+	}
+
+	return resp, nil
+}
+
+func (s *DefaultService) Revoke(ctx context.Context, userId uuid.UUID, id uuid.UUID) (err error) {
+	const op = "service.session.Revoke"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	err = s.repo.Revoke(ctx, id, userId)
+
+	s.audit.Record(ctx, auditDto.Event{
+		Action:  auditSvc.ActionSessionRevoke,
+		Target:  id.String(),
+		Outcome: auditSvc.Outcome(err),
+	})
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+func (s *DefaultService) RevokeAll(ctx context.Context, userId uuid.UUID) (err error) {
+	const op = "service.session.RevokeAll"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	if err := s.repo.RevokeAllByUser(ctx, userId); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Validate returns ErrInactive unless the session exists, isn't revoked and
+// hasn't expired. Valid sessions get their last_seen_at refreshed.
+func (s *DefaultService) Validate(ctx context.Context, id uuid.UUID) (err error) {
+	const op = "service.session.Validate"
+
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	sess, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if sess.RevokedAt != nil || time.Now().After(sess.ExpiresAt) {
+		return fmt.Errorf("%s: %w", op, ErrInactive)
+	}
+
+	if err := s.repo.Touch(ctx, id, touchInterval); err != nil {
+		s.log.WarnContext(ctx, "failed to touch session", slog.String("op", op), slog.String("error", err.Error()))
+	}
+
+	return nil
+}
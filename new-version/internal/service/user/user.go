@@ -2,13 +2,25 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"new-version/internal/config"
+	auditDto "new-version/internal/contract/audit"
 	userDto "new-version/internal/contract/user"
+	"new-version/internal/metrics"
 	userRepo "new-version/internal/repository/user"
+	auditSvc "new-version/internal/service/audit"
 	"new-version/internal/service/auth"
+	sessionSvc "new-version/internal/service/session"
+	"new-version/internal/tracing"
+	"new-version/internal/validator/common"
 	userVal "new-version/internal/validator/user"
+	"new-version/pkg/errs"
+	"new-version/pkg/hasher"
+	"strings"
+
+	"github.com/google/uuid"
 )
 
 type Service interface {
@@ -17,83 +29,171 @@ type Service interface {
 }
 
 type DefaultService struct {
-	log  *slog.Logger
-	repo userRepo.Repository
-	auth auth.Service
-	cfg  *config.Security
+	log      *slog.Logger
+	repo     userRepo.Repository
+	auth     auth.Service
+	authn    auth.Authenticator
+	sessions sessionSvc.Service
+	audit    auditSvc.Service
+	policy   *userVal.PasswordPolicy
+	metrics  *metrics.Metrics
+	cfg      *config.Security
 }
 
 func New(
 	log *slog.Logger,
 	repo userRepo.Repository,
 	auth auth.Service,
+	authn auth.Authenticator,
+	sessions sessionSvc.Service,
+	audit auditSvc.Service,
+	policy *userVal.PasswordPolicy,
+	metrics *metrics.Metrics,
 	cfg *config.Security,
 ) *DefaultService {
 	return &DefaultService{
-		log:  log,
-		repo: repo,
-		auth: auth,
-		cfg:  cfg,
+		log:      log,
+		repo:     repo,
+		auth:     auth,
+		authn:    authn,
+		sessions: sessions,
+		audit:    audit,
+		policy:   policy,
+		metrics:  metrics,
+		cfg:      cfg,
 	}
 }
 
-func (u *DefaultService) Register(ctx context.Context, userReq userDto.Request) error {
+func (u *DefaultService) Register(ctx context.Context, userReq userDto.Request) (err error) {
 	const op = "service.user.Register"
 
-	if !userVal.RightEmailFormat(userReq.Email) {
-		return fmt.Errorf("%s", userVal.WrongEmailFormat(userReq.Email))
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
+
+	userReq.Email = common.NormalizeEmail(userReq.Email)
+
+	if !common.IsEmail(userReq.Email) {
+		return fmt.Errorf("%s: %w", op, errs.Validation("%s", userVal.WrongEmailFormat(userReq.Email)))
 	}
 
-	if res := userVal.ValidatePassword(userReq.Password, u.cfg.PasswordMinLen); res != "" {
-		return fmt.Errorf("%s", res)
+	if failed := u.policy.Validate(userReq.Password, userReq.Email); len(failed) > 0 {
+		return fmt.Errorf("%s: %w", op, errs.Validation("%s", strings.Join(failed, "; ")))
 	}
 
 	pass, err := u.auth.HashPassword(userReq.Password)
 	if err != nil {
+		if errors.Is(err, hasher.ErrPasswordTooLong) {
+			return fmt.Errorf("%s: %w", op, errs.Validation("%s", userVal.TooManyBytes()))
+		}
+
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
 	userReq.Password = pass
 
 	err = u.repo.Create(ctx, userReq)
+
+	u.audit.Record(ctx, auditDto.Event{
+		Actor:   userReq.Email,
+		Action:  auditSvc.ActionRegister,
+		Target:  userReq.Email,
+		Outcome: auditSvc.Outcome(err),
+	})
+
 	if err != nil {
-		u.log.Error("%s: %w", op, err)
+		u.log.ErrorContext(ctx, "failed to register user", slog.String("op", op), slog.String("error", err.Error()))
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	u.metrics.Registered()
+
 	return nil
 }
 
-func (u *DefaultService) Login(ctx context.Context, userReq userDto.Request) (string, error) {
+func (u *DefaultService) Login(ctx context.Context, userReq userDto.Request) (_ string, err error) {
 	const op = "service.user.Login"
 
-	pass, err := u.repo.GetPasswordByEmail(ctx, userReq.Email)
-	if err != nil {
-		return "", fmt.Errorf("%s: %w", op, err)
-	}
+	ctx, span := tracing.Start(ctx, op)
+	defer tracing.End(span, &err)
 
-	valid, err := u.auth.ComparePassword(pass, userReq.Password)
+	userReq.Email = common.NormalizeEmail(userReq.Email)
+
+	identity, err := u.authn.Authenticate(ctx, userReq.Email, userReq.Password)
 	if err != nil {
+		u.audit.Record(ctx, auditDto.Event{
+			Actor:   userReq.Email,
+			Action:  auditSvc.ActionLogin,
+			Target:  userReq.Email,
+			Outcome: auditSvc.OutcomeFailure,
+			Details: err.Error(),
+		})
+		u.metrics.LoggedIn(err)
+
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	if !valid {
-		return "", fmt.Errorf("wrong password")
+	if identity.Source != auth.SourceLocal {
+		identity.Email = common.NormalizeEmail(identity.Email)
+
+		identity.Id, identity.AccessLevel, err = u.syncExternal(ctx, identity)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
 	}
 
-	userInfo, err := u.repo.GetInfoByEmail(ctx, userReq.Email)
+	sessionId, err := u.sessions.Create(ctx, identity.Id, u.cfg.AccessTokenExpire)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	token, err := u.auth.GenerateJwtToken(userDto.Model{
-		Email:       userInfo.Email,
-		Password:    pass,
-		AccessLevel: userInfo.AccessLevel,
+	u.audit.Record(ctx, auditDto.Event{
+		Actor:   identity.Email,
+		Action:  auditSvc.ActionLogin,
+		Target:  identity.Email,
+		Outcome: auditSvc.OutcomeSuccess,
+		Details: "source: " + identity.Source,
 	})
+	u.metrics.LoggedIn(nil)
+
+	token, err := u.auth.GenerateJwtToken(userDto.Model{
+		Id:          identity.Id,
+		Email:       identity.Email,
+		AccessLevel: identity.AccessLevel,
+	}, sessionId)
 	if err != nil {
 		return "", fmt.Errorf("%s: %w", op, err)
 	}
 
 	return token, nil
 }
+
+// syncExternal mirrors a directory user into the users table and records a
+// role change when the directory groups map to a different access level.
+// It returns the local id and access level of the user, which for a local
+// account with the same email stay as they were.
+func (u *DefaultService) syncExternal(ctx context.Context, identity auth.Identity) (uuid.UUID, int, error) {
+	const op = "service.user.syncExternal"
+
+	prev, prevErr := u.repo.GetInfoByEmail(ctx, identity.Email)
+
+	if err := u.repo.SyncExternal(ctx, identity.Email, identity.AccessLevel); err != nil {
+		return uuid.Nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	userInfo, err := u.repo.GetInfoByEmail(ctx, identity.Email)
+	if err != nil {
+		return uuid.Nil, 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if prevErr == nil && prev.AccessLevel != userInfo.AccessLevel {
+		u.audit.Record(ctx, auditDto.Event{
+			Actor:   identity.Source,
+			Action:  auditSvc.ActionRoleChange,
+			Target:  identity.Email,
+			Outcome: auditSvc.OutcomeSuccess,
+			Details: fmt.Sprintf("access level %d -> %d", prev.AccessLevel, userInfo.AccessLevel),
+		})
+	}
+
+	return userInfo.Id, userInfo.AccessLevel, nil
+}
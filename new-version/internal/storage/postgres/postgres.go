@@ -2,11 +2,24 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"new-version/internal/config"
 	"os"
 
+	"github.com/XSAM/otelsql"
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// SchemaVersion is the version database/schema.sql sets.
+const SchemaVersion = 3
+
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeForeignKeyViolation = "23503"
+	codeUniqueViolation     = "23505"
 )
 
 type Storage struct {
@@ -25,7 +38,11 @@ func New(cfg *config.Database) (*Storage, error) {
 		cfg.Port,
 	)
 
-	db, err := sql.Open("pgx", dbUrl)
+	// queries are traced with their statement, parameters are never recorded
+	db, err := otelsql.Open("pgx", dbUrl,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{OmitConnResetSession: true, OmitRows: true}),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
@@ -46,3 +63,19 @@ func New(cfg *config.Database) (*Storage, error) {
 func (s *Storage) DB() *sql.DB {
 	return s.db
 }
+
+// IsUniqueViolation reports whether err was caused by a unique constraint.
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, codeUniqueViolation)
+}
+
+// IsForeignKeyViolation reports whether err was caused by a foreign key, e.g.
+// when deleting a row that is still referenced.
+func IsForeignKeyViolation(err error) bool {
+	return hasCode(err, codeForeignKeyViolation)
+}
+
+func hasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
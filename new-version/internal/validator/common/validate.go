@@ -0,0 +1,361 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"new-version/pkg/errs"
+)
+
+// Validate checks the rules declared in the `validate` tags of the struct v
+// points to and returns an errs.InvalidFields error listing every failed
+// field, or nil. Rules are separated by commas:
+//
+//	required     the field isn't the zero value
+//	min=N max=N  length of strings and slices, value of numbers
+//	oneof=a|b    the value is one of the listed ones
+//	email        a valid email address
+//	isbn         a valid ISBN-10 or ISBN-13, hyphens and spaces allowed
+//	notfuture    a year or time that isn't in the future
+//
+// Rules other than required are skipped for zero values, so optional fields
+// are only checked when set. Fields are reported by their json names.
+//
+// Tags that can't be checked, like unknown rules, are reported as a plain
+// error, which handlers answer with 500. CheckRules finds them ahead of time.
+func Validate(v any) error {
+	if err := CheckRules(v); err != nil {
+		return err
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+
+	var failed []errs.FieldError
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+
+		if msg := checkField(rv.Field(i), tag); msg != "" {
+			failed = append(failed, errs.FieldError{Field: fieldName(sf), Message: msg})
+		}
+	}
+
+	if len(failed) > 0 {
+		return errs.InvalidFields(failed)
+	}
+
+	return nil
+}
+
+var checked sync.Map // reflect.Type -> error
+
+// CheckRules reports the rules in the `validate` tags of the struct v points
+// to that Validate can't check: unknown rules, bad arguments and rules on
+// fields of a kind they don't apply to. Results are cached per type.
+func CheckRules(v any) error {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return fmt.Errorf("validator: %T is not a struct", v)
+	}
+
+	if err, ok := checked.Load(rt); ok {
+		err, _ := err.(error)
+		return err
+	}
+
+	var problems []error
+
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			if err := checkRule(sf.Type, rule); err != nil {
+				problems = append(problems, fmt.Errorf("validator: %s.%s: %w", rt.Name(), sf.Name, err))
+			}
+		}
+	}
+
+	err := errors.Join(problems...)
+	checked.Store(rt, err)
+
+	return err
+}
+
+func checkRule(ft reflect.Type, rule string) error {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	if _, ok := checks[name]; !ok {
+		return fmt.Errorf("unknown rule %q", name)
+	}
+
+	switch name {
+	case "min", "max":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("bad argument of %s: %q", name, arg)
+		}
+
+		if !sized(ft) {
+			return fmt.Errorf("%s on %s", name, ft.Kind())
+		}
+	case "oneof":
+		if arg == "" {
+			return errors.New("oneof without values")
+		}
+	case "email", "isbn":
+		if ft.Kind() != reflect.String {
+			return fmt.Errorf("%s on %s", name, ft.Kind())
+		}
+	case "notfuture":
+		if ft != reflect.TypeOf(time.Time{}) && !isInt(ft) {
+			return fmt.Errorf("notfuture on %s", ft)
+		}
+	}
+
+	return nil
+}
+
+func sized(ft reflect.Type) bool {
+	switch ft.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array, reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return isInt(ft)
+}
+
+func isInt(ft reflect.Type) bool {
+	switch ft.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+
+	return false
+}
+
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+
+	return name
+}
+
+// checkField returns the message of the first rule fv breaks. The rules have
+// been checked by CheckRules.
+func checkField(fv reflect.Value, tag string) string {
+	rules := strings.Split(tag, ",")
+
+	if fv.IsZero() {
+		for _, rule := range rules {
+			if rule == "required" {
+				return "is required"
+			}
+		}
+
+		return ""
+	}
+
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		if msg := checks[name](fv, arg); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+var checks = map[string]func(fv reflect.Value, arg string) string{
+	"required":  func(reflect.Value, string) string { return "" },
+	"min":       checkMin,
+	"max":       checkMax,
+	"oneof":     checkOneOf,
+	"email":     checkEmail,
+	"isbn":      checkISBN,
+	"notfuture": checkNotFuture,
+}
+
+// size returns the length of strings and slices and the value of numbers.
+func size(fv reflect.Value) float64 {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(fv.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fv.Float()
+	default:
+		return 0
+	}
+}
+
+// unit names what min and max count for fv, or "" for numbers.
+func unit(fv reflect.Value) string {
+	switch fv.Kind() {
+	case reflect.String:
+		return "characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "items"
+	default:
+		return ""
+	}
+}
+
+func float(arg string) float64 {
+	n, _ := strconv.ParseFloat(arg, 64)
+	return n
+}
+
+func checkMin(fv reflect.Value, arg string) string {
+	if size(fv) >= float(arg) {
+		return ""
+	}
+
+	if u := unit(fv); u != "" {
+		return fmt.Sprintf("must have at least %s %s", arg, u)
+	}
+
+	return fmt.Sprintf("must be at least %s", arg)
+}
+
+func checkMax(fv reflect.Value, arg string) string {
+	if size(fv) <= float(arg) {
+		return ""
+	}
+
+	if u := unit(fv); u != "" {
+		return fmt.Sprintf("must have at most %s %s", arg, u)
+	}
+
+	return fmt.Sprintf("must be at most %s", arg)
+}
+
+func checkOneOf(fv reflect.Value, arg string) string {
+	allowed := strings.Split(arg, "|")
+
+	value := fmt.Sprint(fv.Interface())
+	for _, a := range allowed {
+		if value == a {
+			return ""
+		}
+	}
+
+	return "must be one of: " + strings.Join(allowed, ", ")
+}
+
+func checkEmail(fv reflect.Value, _ string) string {
+	if !IsEmail(fv.String()) {
+		return "must be a valid email address"
+	}
+
+	return ""
+}
+
+// IsEmail reports whether s is a bare email address, without a display name
+// or angle brackets.
+func IsEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
+
+// NormalizeEmail trims s and lowercases it, so the same mailbox always maps to
+// the same users row.
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func checkISBN(fv reflect.Value, _ string) string {
+	if !IsISBN(fv.String()) {
+		return "must be a valid ISBN"
+	}
+
+	return ""
+}
+
+func checkNotFuture(fv reflect.Value, _ string) string {
+	if t, ok := fv.Interface().(time.Time); ok {
+		if t.After(time.Now()) {
+			return "can't be in the future"
+		}
+
+		return ""
+	}
+
+	if int(size(fv)) > time.Now().Year() {
+		return "can't be later than the current year"
+	}
+
+	return ""
+}
+
+// IsISBN reports whether s is an ISBN-10 or ISBN-13 with a valid check digit.
+func IsISBN(s string) bool {
+	s = strings.NewReplacer("-", "", " ", "").Replace(s)
+
+	switch len(s) {
+	case 10:
+		sum := 0
+		for i, c := range s {
+			var d int
+			switch {
+			case c >= '0' && c <= '9':
+				d = int(c - '0')
+			case (c == 'X' || c == 'x') && i == 9:
+				d = 10
+			default:
+				return false
+			}
+
+			sum += d * (10 - i)
+		}
+
+		return sum%11 == 0
+	case 13:
+		sum := 0
+		for i, c := range s {
+			if c < '0' || c > '9' {
+				return false
+			}
+
+			d := int(c - '0')
+			if i%2 == 1 {
+				d *= 3
+			}
+
+			sum += d
+		}
+
+		return sum%10 == 0
+	default:
+		return false
+	}
+}
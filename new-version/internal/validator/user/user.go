@@ -1,74 +1,242 @@
 package user
 
 import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
-	"net/mail"
+	"new-version/internal/config"
+	"os"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// MaxPasswordBytes is the longest password bcrypt accepts. The policy checks it
+// on top of MaxLen, which counts characters: 40 Cyrillic letters are 80 bytes.
+const MaxPasswordBytes = 72
+
 // Messages
 func WrongEmailFormat(email string) string {
 	return fmt.Sprintf("wrong email format: %s", email)
 }
 
-func TooShort(pass string) string {
-	return fmt.Sprintf("password is too short: %s", pass)
+func TooShort(minLen int) string {
+	return fmt.Sprintf("password must be at least %d characters long", minLen)
 }
 
-func HasNoNumber(pass string) string {
-	return fmt.Sprintf("password has no number: %s", pass)
+func TooLong(maxLen int) string {
+	return fmt.Sprintf("password must be at most %d characters long", maxLen)
 }
 
-func HasNoCapitalizedLetter(pass string) string {
-	return fmt.Sprintf("password has no capitalized letter: %s", pass)
+func TooManyBytes() string {
+	return fmt.Sprintf("password must be at most %d bytes long, fewer for non-Latin letters", MaxPasswordBytes)
 }
 
-func HasNoSpecialSymbol(pass string) string {
-	return fmt.Sprintf("password has no special symbol: %s", pass)
+func HasNoNumber() string {
+	return "password must contain a number"
 }
 
-// Validators
-func RightEmailFormat(email string) bool {
-	_, err := mail.ParseAddress(email)
-	return err == nil
+func HasNoCapitalizedLetter() string {
+	return "password must contain a capital letter"
+}
+
+func HasNoLowercaseLetter() string {
+	return "password must contain a lowercase letter"
+}
+
+func HasNoSpecialSymbol() string {
+	return "password must contain a special symbol"
+}
+
+func TooManyRepeated(maxRepeated int) string {
+	return fmt.Sprintf("password must not repeat the same character more than %d times in a row", maxRepeated)
+}
+
+func SimilarToEmail() string {
+	return "password must not be similar to the email"
+}
+
+func Breached() string {
+	return "password appears in a list of breached passwords"
 }
 
+// Validators
 func IsShort(pass string, minLen int) bool {
-	return len(pass) < minLen
+	return utf8.RuneCountInString(pass) < minLen
+}
+
+func IsLong(pass string, maxLen int) bool {
+	return maxLen > 0 && utf8.RuneCountInString(pass) > maxLen
+}
+
+func IsTooManyBytes(pass string) bool {
+	return len(pass) > MaxPasswordBytes
 }
 
 func HasNumber(pass string) bool {
-	return strings.ContainsAny(pass, "1234567890")
+	return strings.IndexFunc(pass, unicode.IsDigit) >= 0
 }
 
 func HasCapitalizedLetter(pass string) bool {
-	return strings.ContainsAny(pass, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	return strings.IndexFunc(pass, unicode.IsUpper) >= 0
+}
+
+func HasLowercaseLetter(pass string) bool {
+	return strings.IndexFunc(pass, unicode.IsLower) >= 0
 }
 
 func HasSpecialSymbol(pass string) bool {
-	return strings.ContainsAny(pass, "@#$%&/?.,-_+=)(^;:!")
+	return strings.IndexFunc(pass, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r)
+	}) >= 0
+}
+
+// LongestRun returns the length of the longest run of one repeated character.
+func LongestRun(pass string) int {
+	longest, run := 0, 0
+
+	var prev rune
+	for i, r := range []rune(pass) {
+		if i > 0 && r == prev {
+			run++
+		} else {
+			run = 1
+		}
+
+		prev = r
+		longest = max(longest, run)
+	}
+
+	return longest
+}
+
+// IsSimilarToEmail reports whether the password contains the local part of
+// the email (or the other way round), ignoring case.
+func IsSimilarToEmail(pass string, email string) bool {
+	pass = strings.ToLower(pass)
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+
+	if len(local) < 3 || len(pass) < 3 {
+		return pass == local
+	}
+
+	return strings.Contains(pass, local) || strings.Contains(local, pass)
+}
+
+type PasswordPolicy struct {
+	cfg      *config.PasswordPolicy
+	breached map[[sha1.Size]byte]struct{}
+}
+
+func NewPasswordPolicy(cfg *config.PasswordPolicy) (*PasswordPolicy, error) {
+	const op = "validator.user.NewPasswordPolicy"
+
+	p := &PasswordPolicy{cfg: cfg}
+
+	if cfg.BreachedListPath == "" {
+		return p, nil
+	}
+
+	breached, err := loadBreachedList(cfg.BreachedListPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	p.breached = breached
+
+	return p, nil
+}
+
+// loadBreachedList reads one entry per line, either a plain password or its
+// SHA-1 as hex, optionally followed by ":<count>". Empty lines and lines
+// starting with # are skipped.
+func loadBreachedList(path string) (map[[sha1.Size]byte]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	breached := make(map[[sha1.Size]byte]struct{})
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum [sha1.Size]byte
+
+		h, _, _ := strings.Cut(line, ":")
+		if b, err := hex.DecodeString(h); err == nil && len(b) == sha1.Size {
+			copy(sum[:], b)
+		} else {
+			sum = sha1.Sum([]byte(line))
+		}
+
+		breached[sum] = struct{}{}
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	return breached, nil
 }
 
-func ValidatePassword(pass string, passMinLen int) string {
-	if IsShort(pass, passMinLen) {
-		return fmt.Sprintf("%s", TooShort(pass))
+func (p *PasswordPolicy) IsBreached(pass string) bool {
+	_, ok := p.breached[sha1.Sum([]byte(pass))]
+	return ok
+}
+
+// Validate checks pass against every rule and returns the messages of all
+// failed ones. The messages never contain the password itself.
+func (p *PasswordPolicy) Validate(pass string, email string) []string {
+	var failed []string
+
+	if IsShort(pass, p.cfg.MinLen) {
+		failed = append(failed, TooShort(p.cfg.MinLen))
+	}
+
+	if IsLong(pass, p.cfg.MaxLen) {
+		failed = append(failed, TooLong(p.cfg.MaxLen))
+	} else if IsTooManyBytes(pass) {
+		failed = append(failed, TooManyBytes())
+	}
+
+	if p.cfg.RequireDigit && !HasNumber(pass) {
+		failed = append(failed, HasNoNumber())
+	}
+
+	if p.cfg.RequireUpper && !HasCapitalizedLetter(pass) {
+		failed = append(failed, HasNoCapitalizedLetter())
+	}
+
+	if p.cfg.RequireLower && !HasLowercaseLetter(pass) {
+		failed = append(failed, HasNoLowercaseLetter())
+	}
+
+	if p.cfg.RequireSpecial && !HasSpecialSymbol(pass) {
+		failed = append(failed, HasNoSpecialSymbol())
 	}
 
-	if !HasNumber(pass) {
-		return fmt.Sprintf("%s", HasNoNumber(pass))
+	if p.cfg.MaxRepeated > 0 && LongestRun(pass) > p.cfg.MaxRepeated {
+		failed = append(failed, TooManyRepeated(p.cfg.MaxRepeated))
 	}
 
-	if !HasCapitalizedLetter(pass) {
-		return fmt.Sprintf("%s", HasNoCapitalizedLetter(pass))
+	if p.cfg.RejectEmailSimilar && IsSimilarToEmail(pass, email) {
+		failed = append(failed, SimilarToEmail())
 	}
 
-	if !HasSpecialSymbol(pass) {
-		return fmt.Sprintf("%s", HasNoSpecialSymbol(pass))
+	if p.IsBreached(pass) {
+		failed = append(failed, Breached())
 	}
 
-	return ""
+	return failed
 }
 
 func ValidateJwt(jwtSecret string, signedToken string) (jwt.MapClaims, error) {
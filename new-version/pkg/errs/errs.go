@@ -0,0 +1,95 @@
+// Package errs defines the kinds of domain errors. Repositories and services
+// return them, wrapped with their op as usual, and the HTTP layer maps the
+// kind to a status code, see httphelpers.StatusFromError.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrForbidden    = errors.New("forbidden")
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrStale is returned when a write expected a version of a record that
+	// has been replaced in the meantime.
+	ErrStale = errors.New("stale version")
+)
+
+// Error is a domain error of Kind with a message that is safe to show to
+// clients. Validation errors may list the offending Fields.
+type Error struct {
+	Kind   error
+	Msg    string
+	Fields []FieldError
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+func newError(kind error, format string, args ...any) error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, args...)}
+}
+
+func NotFound(format string, args ...any) error {
+	return newError(ErrNotFound, format, args...)
+}
+
+func Conflict(format string, args ...any) error {
+	return newError(ErrConflict, format, args...)
+}
+
+func Validation(format string, args ...any) error {
+	return newError(ErrValidation, format, args...)
+}
+
+// InvalidFields is a validation error listing every invalid field.
+func InvalidFields(fields []FieldError) error {
+	return &Error{Kind: ErrValidation, Msg: "request has invalid fields", Fields: fields}
+}
+
+func Forbidden(format string, args ...any) error {
+	return newError(ErrForbidden, format, args...)
+}
+
+func Unauthorized(format string, args ...any) error {
+	return newError(ErrUnauthorized, format, args...)
+}
+
+func Stale(format string, args ...any) error {
+	return newError(ErrStale, format, args...)
+}
+
+// Message returns the client message of the domain error in err's chain.
+// ok is false when err isn't a domain error.
+func Message(err error) (msg string, ok bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Msg, true
+	}
+
+	return "", false
+}
+
+// Fields returns the invalid fields of the domain error in err's chain.
+func Fields(err error) []FieldError {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Fields
+	}
+
+	return nil
+}
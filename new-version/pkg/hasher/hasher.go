@@ -0,0 +1,228 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+var (
+	ErrUnknownFormat   = errors.New("unknown password hash format")
+	ErrPasswordTooLong = errors.New("password is too long to hash")
+)
+
+// Hasher produces encoded password hashes. Every implementation verifies
+// hashes of all supported formats, so stored hashes keep working after the
+// configured algorithm or its parameters change; NeedsRehash reports those.
+type Hasher interface {
+	Hash(pass string) (string, error)
+	Verify(encoded string, pass string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+type Options struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+func New(opts Options) (Hasher, error) {
+	switch opts.Algorithm {
+	case "", AlgBcrypt:
+		cost := opts.BcryptCost
+		if cost == 0 {
+			cost = bcrypt.DefaultCost
+		}
+
+		if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+
+		return &Bcrypt{Cost: cost}, nil
+	case AlgArgon2id:
+		p := opts.Argon2
+		if p.Memory == 0 || p.Iterations == 0 || p.Parallelism == 0 || p.SaltLength == 0 || p.KeyLength == 0 {
+			return nil, errors.New("argon2id memory, iterations, parallelism, salt and key length must be set")
+		}
+
+		return &Argon2id{Params: p}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported password hashing algorithm %q", opts.Algorithm)
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func isArgon2id(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func verify(encoded string, pass string) (bool, error) {
+	switch {
+	case isBcrypt(encoded):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(pass))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	case isArgon2id(encoded):
+		p, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+
+		other := argon2.IDKey([]byte(pass), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+
+		return subtle.ConstantTimeCompare(key, other) == 1, nil
+	}
+
+	return false, ErrUnknownFormat
+}
+
+type Bcrypt struct {
+	Cost int
+}
+
+func (b *Bcrypt) Hash(pass string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(pass), b.Cost)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return "", ErrPasswordTooLong
+		}
+
+		return "", err
+	}
+
+	return string(h), nil
+}
+
+func (b *Bcrypt) Verify(encoded string, pass string) (bool, error) {
+	return verify(encoded, pass)
+}
+
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+
+	return err != nil || cost != b.Cost
+}
+
+// Argon2Params are the argon2id tuning knobs. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+type Argon2id struct {
+	Params Argon2Params
+}
+
+// Hash returns the hash in PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func (a *Argon2id) Hash(pass string) (string, error) {
+	salt := make([]byte, a.Params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	p := a.Params
+	key := argon2.IDKey([]byte(pass), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(encoded string, pass string) (bool, error) {
+	return verify(encoded, pass)
+}
+
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	if !isArgon2id(encoded) {
+		return true
+	}
+
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return p.Memory != a.Params.Memory ||
+		p.Iterations != a.Params.Iterations ||
+		p.Parallelism != a.Params.Parallelism ||
+		uint32(len(salt)) != a.Params.SaltLength ||
+		uint32(len(key)) != a.Params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgArgon2id {
+		return Argon2Params{}, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id version: %w", err)
+	}
+
+	if version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	var p Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id parameters: %w", err)
+	}
+
+	// argon2.IDKey panics on these, e.g. for a corrupted or badly imported row
+	if p.Iterations < 1 || p.Parallelism < 1 || p.Memory < 8*uint32(p.Parallelism) {
+		return Argon2Params{}, nil, nil, fmt.Errorf("invalid argon2id parameters m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2Params{}, nil, nil, fmt.Errorf("argon2id key: %w", err)
+	}
+
+	if len(key) == 0 {
+		return Argon2Params{}, nil, nil, errors.New("argon2id key is empty")
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+
+	return p, salt, key, nil
+}
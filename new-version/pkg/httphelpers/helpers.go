@@ -1,7 +1,11 @@
 package httphelpers
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"new-version/pkg/errs"
 	"strconv"
 )
 
@@ -26,6 +30,66 @@ func NewResponse(message string, data any, status int) Response {
 	}
 }
 
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+
+	Errors []errs.FieldError `json:"errors,omitempty"`
+}
+
+const problemTypePrefix = "urn:inai-library:problem:"
+
+var problemTypes = map[int]string{
+	http.StatusBadRequest:           "bad-request",
+	http.StatusUnauthorized:         "unauthorized",
+	http.StatusForbidden:            "forbidden",
+	http.StatusNotFound:             "not-found",
+	http.StatusConflict:             "conflict",
+	http.StatusUnprocessableEntity:  "validation",
+	http.StatusPreconditionFailed:   "precondition-failed",
+	http.StatusPreconditionRequired: "precondition-required",
+	http.StatusTooManyRequests:      "rate-limited",
+	http.StatusInternalServerError:  "internal",
+}
+
+// NewProblem describes a failure of r with status. Statuses without a
+// problem type of their own get "about:blank", as RFC 7807 suggests.
+func NewProblem(r *http.Request, status int, detail string) Problem {
+	typ := "about:blank"
+	if t, ok := problemTypes[status]; ok {
+		typ = problemTypePrefix + t
+	}
+
+	return Problem{
+		Type:      typ,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		RequestID: RequestID(r.Context()),
+	}
+}
+
+const HeaderRequestID = "X-Request-ID"
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id given to the request by the request id
+// middleware, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
 func NewErrResponse(message string, status int) Response {
 	return Response{
 		Message: message,
@@ -43,3 +107,33 @@ func ParseIntIdFromPath(r *http.Request) (int, error) {
 
 	return id, nil
 }
+
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// StatusFromError maps the kind of a domain error to its status code.
+// Errors of no known kind are internal server errors.
+func StatusFromError(err error) int {
+	switch {
+	case errors.Is(err, errs.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errs.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, errs.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, errs.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, errs.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, errs.ErrStale):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
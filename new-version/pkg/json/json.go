@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"new-version/pkg/errs"
 	help "new-version/pkg/httphelpers"
 )
 
@@ -17,8 +18,17 @@ func ReadRequestBody(r *http.Request, result any) error {
 	return nil
 }
 
+const (
+	contentType        = "application/json"
+	problemContentType = "application/problem+json"
+)
+
 func WriteResponseBody(w http.ResponseWriter, data any, statusCode int) {
-	w.Header().Set("Content-Type", "application-json")
+	writeBody(w, contentType, data, statusCode)
+}
+
+func writeBody(w http.ResponseWriter, contentType string, data any, statusCode int) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(statusCode)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -31,7 +41,41 @@ func WriteSuccess(w http.ResponseWriter, msg string, data any, code int) {
 	WriteResponseBody(w, resp, code)
 }
 
-func WriteError(w http.ResponseWriter, msg string, code int) {
-	resp := help.NewErrResponse(msg, code)
-	WriteResponseBody(w, resp, code)
+// WriteError writes an application/problem+json response for r. detail is
+// sent to the client as is.
+func WriteError(w http.ResponseWriter, r *http.Request, detail string, code int) {
+	writeBody(w, problemContentType, help.NewProblem(r, code, detail), code)
+}
+
+// WriteBadRequest answers 400 with detail. err, e.g. of the decoder, tells
+// more about the server than the client needs to know and is only logged.
+func WriteBadRequest(w http.ResponseWriter, r *http.Request, detail string, err error) {
+	slog.InfoContext(r.Context(), "bad request",
+		slog.String("detail", detail),
+		slog.String("error", err.Error()),
+		slog.String("path", r.URL.Path),
+	)
+
+	WriteError(w, r, detail, http.StatusBadRequest)
+}
+
+// WriteErrorFrom writes err with the status of its kind. Only the messages
+// of domain errors reach the client, other errors are logged and reported
+// as internal server errors.
+func WriteErrorFrom(w http.ResponseWriter, r *http.Request, err error) {
+	detail, ok := errs.Message(err)
+	if !ok {
+		slog.ErrorContext(r.Context(), "internal server error",
+			slog.String("error", err.Error()),
+			slog.String("path", r.URL.Path),
+		)
+
+		WriteError(w, r, "", http.StatusInternalServerError)
+		return
+	}
+
+	problem := help.NewProblem(r, help.StatusFromError(err), detail)
+	problem.Errors = errs.Fields(err)
+
+	writeBody(w, problemContentType, problem, problem.Status)
 }
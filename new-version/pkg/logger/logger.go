@@ -16,17 +16,17 @@ func SetupLogger(env string) *slog.Logger {
 
 	switch env {
 	case envDev:
-		log = slog.New(slog.NewTextHandler(
+		log = slog.New(NewContextHandler(slog.NewTextHandler(
 			os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug},
-		))
+		)))
 	case envLocal:
-		log = slog.New(slog.NewTextHandler(
+		log = slog.New(NewContextHandler(slog.NewTextHandler(
 			os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo},
-		))
+		)))
 	case envProd:
-		log = slog.New(slog.NewJSONHandler(
+		log = slog.New(NewContextHandler(slog.NewJSONHandler(
 			os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo},
-		))
+		)))
 	}
 
 	return log
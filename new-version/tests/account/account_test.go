@@ -0,0 +1,95 @@
+package account_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	accountRepo "new-version/internal/repository/account"
+	auditRepo "new-version/internal/repository/audit"
+	userRepo "new-version/internal/repository/user"
+	accountSvc "new-version/internal/service/account"
+	auditSvc "new-version/internal/service/audit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountRepository_Purge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := accountRepo.New(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE reviews SET author_id = NULL WHERE author_id = $1`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM notifications WHERE recipient_id = $1`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 5))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM reservations WHERE owner_id = $1`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions WHERE user_id = $1`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id = $1 AND deletion_requested_at IS NOT NULL`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.Purge(context.Background(), id))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccountRepository_PurgeRestored(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := accountRepo.New(db)
+	id := uuid.New()
+
+	mock.ExpectBegin()
+	for i := 0; i < 4; i++ {
+		mock.ExpectExec(".*").WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users`)).
+		WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	require.Error(t, repo.Purge(context.Background(), id))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccountService_RequestDeletionWithLoans(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := accountSvc.New(
+		log,
+		accountRepo.New(db),
+		userRepo.New(db),
+		auditSvc.New(log, auditRepo.New(db)),
+		&config.Privacy{DeletionGracePeriod: 720 * time.Hour},
+	)
+
+	id := uuid.New()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM reservations`)).
+		WithArgs(id).
+		WillReturnRows(mock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_events`)).
+		WithArgs(sqlmock.AnyArg(), "", "", "account.delete", id.String(), "", "", "failure", accountSvc.ErrOutstandingLoans.Error()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	_, err = svc.RequestDeletion(context.Background(), id)
+	require.True(t, errors.Is(err, accountSvc.ErrOutstandingLoans))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
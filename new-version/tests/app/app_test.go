@@ -0,0 +1,115 @@
+package app_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"new-version/internal/app"
+
+	"github.com/stretchr/testify/require"
+)
+
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (e *events) add(s string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.list = append(e.list, s)
+}
+
+func (e *events) get() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	return l.Addr().String()
+}
+
+func newApp() *app.App {
+	return app.New(slog.New(slog.NewTextHandler(io.Discard, nil)), 5*time.Second)
+}
+
+func TestShutdownOrder(t *testing.T) {
+	var ev events
+
+	started := make(chan struct{})
+	addr := freeAddr(t)
+
+	a := newApp()
+	a.AddServer("http", &http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		ev.add("request done")
+	})})
+
+	for _, name := range []string{"worker-a", "worker-b"} {
+		a.AddWorker(name, func(ctx context.Context) {
+			<-ctx.Done()
+			ev.add(name + " stopped")
+		})
+	}
+
+	a.OnClose("database", func(context.Context) error { ev.add("database closed"); return nil })
+	a.OnClose("tracing", func(context.Context) error { ev.add("tracing closed"); return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() { result <- a.Run(ctx) }()
+
+	status := make(chan int)
+	go func() {
+		// the server starts listening asynchronously
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			resp, err := http.Get("http://" + addr)
+			if err == nil {
+				resp.Body.Close()
+				status <- resp.StatusCode
+				return
+			}
+		}
+
+		close(started)
+		status <- 0
+	}()
+
+	<-started
+	cancel()
+
+	require.NoError(t, <-result)
+	require.Equal(t, http.StatusOK, <-status)
+	require.Equal(t, []string{
+		"request done",
+		"worker-b stopped",
+		"worker-a stopped",
+		"tracing closed",
+		"database closed",
+	}, ev.get())
+}
+
+func TestServerFailure(t *testing.T) {
+	closed := false
+
+	a := newApp()
+	a.AddServer("http", &http.Server{Addr: "127.0.0.1:-1"})
+	a.OnClose("database", func(context.Context) error { closed = true; return nil })
+
+	err := a.Run(context.Background())
+
+	require.ErrorContains(t, err, "server http")
+	require.True(t, closed)
+}
@@ -0,0 +1,77 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	auditDto "new-version/internal/contract/audit"
+	auditHdl "new-version/internal/http/handler/audit"
+
+	"github.com/stretchr/testify/require"
+)
+
+// service returns n copies of event, up to the requested limit.
+type service struct {
+	event auditDto.Event
+	n     int
+	limit int
+}
+
+func (s *service) Record(context.Context, auditDto.Event) {}
+
+func (s *service) List(_ context.Context, filter auditDto.Filter) ([]auditDto.Event, error) {
+	s.limit = filter.Limit
+
+	n := s.n
+	if filter.Limit > 0 && n > filter.Limit {
+		n = filter.Limit
+	}
+
+	events := make([]auditDto.Event, n)
+	for i := range events {
+		events[i] = s.event
+	}
+
+	return events, nil
+}
+
+func TestExportEvents(t *testing.T) {
+	svc := &service{n: 1, event: auditDto.Event{
+		Id:        1,
+		Actor:     "=cmd|' /C calc'!A0",
+		Action:    "user.login",
+		Target:    "-2+3",
+		IP:        "10.0.0.1",
+		UserAgent: `=HYPERLINK("http://evil.example","x")`,
+		Outcome:   "failure",
+		Details:   "@SUM(1)",
+	}}
+
+	w := httptest.NewRecorder()
+	auditHdl.New(nil, svc, nil).ExportEvents(w, httptest.NewRequest(http.MethodGet, "/audit/events/export", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Positive(t, svc.limit)
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	row := rows[1]
+	require.Equal(t, "'=cmd|' /C calc'!A0", row[2])
+	require.Equal(t, "user.login", row[4])
+	require.Equal(t, "'-2+3", row[5])
+	require.Equal(t, "10.0.0.1", row[6])
+	require.Equal(t, `'=HYPERLINK("http://evil.example","x")`, row[7])
+	require.Equal(t, "'@SUM(1)", row[9])
+}
+
+func TestExportEventsTooMany(t *testing.T) {
+	svc := &service{n: 20000}
+
+	w := httptest.NewRecorder()
+	auditHdl.New(nil, svc, nil).ExportEvents(w, httptest.NewRequest(http.MethodGet, "/audit/events/export", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,96 @@
+package audit_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	auditDto "new-version/internal/contract/audit"
+	auditRepo "new-version/internal/repository/audit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+var columns = []string{"id", "occurred_at", "actor", "impersonator", "action", "target", "ip", "user_agent", "outcome", "details"}
+
+func TestAuditRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := auditRepo.New(db)
+
+	event := auditDto.Event{
+		OccurredAt:   time.Now(),
+		Actor:        "aibek@inai.kg",
+		Impersonator: "admin@inai.kg",
+		Action:       "book_category.delete",
+		Target:       "3",
+		IP:           "10.0.0.7",
+		UserAgent:    "Mozilla/5.0",
+		Outcome:      "success",
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO audit_events(occurred_at, actor, impersonator, action, target, ip, user_agent, outcome, details)`)).
+		WithArgs(event.OccurredAt, event.Actor, event.Impersonator, event.Action, event.Target, event.IP, event.UserAgent, event.Outcome, event.Details).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, repo.Create(context.Background(), event))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_GetList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := auditRepo.New(db)
+
+	from := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	tn := time.Now()
+
+	rows := mock.NewRows(columns).
+		AddRow(2, tn, "aibek@inai.kg", "", "user.login", "aibek@inai.kg", "10.0.0.7", "curl/8.0", "failure", "invalid credentials").
+		AddRow(1, tn.Add(-time.Minute), "aibek@inai.kg", "", "user.login", "aibek@inai.kg", "10.0.0.7", "curl/8.0", "failure", "invalid credentials")
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT id, occurred_at, actor, impersonator, action, target, ip, user_agent, outcome, details FROM audit_events `+
+			`WHERE action = $1 AND outcome = $2 AND occurred_at >= $3 ORDER BY occurred_at DESC, id DESC LIMIT $4 OFFSET $5`)).
+		WithArgs("user.login", "failure", from, 20, 40).
+		WillReturnRows(rows)
+
+	events, err := repo.GetList(context.Background(), auditDto.Filter{
+		Action:  "user.login",
+		Outcome: "failure",
+		From:    from,
+		Limit:   20,
+		Offset:  40,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, int64(2), events[0].Id)
+	require.Equal(t, "invalid credentials", events[1].Details)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_GetListUnfiltered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := auditRepo.New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT id, occurred_at, actor, impersonator, action, target, ip, user_agent, outcome, details FROM audit_events ORDER BY occurred_at DESC, id DESC`)).
+		WithoutArgs().
+		WillReturnRows(mock.NewRows(columns))
+
+	events, err := repo.GetList(context.Background(), auditDto.Filter{})
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
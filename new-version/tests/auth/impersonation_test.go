@@ -0,0 +1,85 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	userDto "new-version/internal/contract/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwChain "new-version/internal/http/middleware/chain"
+	authSvc "new-version/internal/service/auth"
+	"new-version/pkg/hasher"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationToken(t *testing.T) {
+	cfg := &config.Security{
+		JwtSecret:           "test-secret",
+		AccessTokenExpire:   time.Hour,
+		ImpersonationExpire: 30 * time.Minute,
+	}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	jwt := authSvc.New(nil, cfg, h)
+
+	student := userDto.Model{Id: uuid.New(), Email: "aibek@inai.kg", AccessLevel: 50}
+	admin := userDto.Model{Id: uuid.New(), Email: "admin@inai.kg", AccessLevel: 100}
+	sessionId := uuid.New()
+
+	own, err := jwt.GenerateJwtToken(student, sessionId)
+	require.NoError(t, err)
+
+	impersonated, err := jwt.GenerateImpersonationToken(student, admin, sessionId)
+	require.NoError(t, err)
+
+	request := func(token string) *http.Request {
+		r := httptest.NewRequest(http.MethodDelete, "/user/me", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		return r
+	}
+
+	p, err := mwAuth.PrincipalFromRequest(request(impersonated), cfg.JwtSecret)
+	require.NoError(t, err)
+	require.True(t, p.Impersonated())
+	require.Equal(t, student.Id, p.UserId)
+	require.Equal(t, student.Email, p.Email)
+	require.Equal(t, admin.Id, p.ImpersonatorId)
+	require.Equal(t, admin.Email, p.Impersonator)
+
+	a := mwAuth.New(cfg.JwtSecret, activeSessions{})
+
+	var got mwAuth.Principal
+	handler := mwChain.Chain(
+		http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got, _ = mwAuth.PrincipalFromContext(r.Context())
+		}),
+		a.Require(50),
+		a.NoImpersonation,
+	)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, request(impersonated))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Zero(t, got)
+
+	p, err = mwAuth.PrincipalFromRequest(request(own), cfg.JwtSecret)
+	require.NoError(t, err)
+	require.False(t, p.Impersonated())
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, request(own))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, p, got)
+}
+
+type activeSessions struct{}
+
+func (activeSessions) Validate(context.Context, uuid.UUID) error { return nil }
@@ -0,0 +1,212 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	authSvc "new-version/internal/service/auth"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	serviceDN   = "cn=library,ou=services,dc=inai,dc=kg"
+	servicePass = "service-secret"
+	staffGroup  = "cn=staff,ou=groups,dc=inai,dc=kg"
+	adminGroup  = "cn=library-admins,ou=groups,dc=inai,dc=kg"
+)
+
+type directoryEntry struct {
+	dn     string
+	mail   string
+	pass   string
+	groups []string
+}
+
+// directory is an in-process stand-in for an LDAP server.
+type directory struct {
+	entries []directoryEntry
+	binds   []string
+	closed  int
+}
+
+func (d *directory) dial(ctx context.Context) (authSvc.LDAPConn, error) {
+	return d, nil
+}
+
+func (d *directory) Bind(username, password string) error {
+	d.binds = append(d.binds, username)
+
+	if username == serviceDN && password == servicePass {
+		return nil
+	}
+
+	for _, e := range d.entries {
+		if e.dn == username && e.pass == password {
+			return nil
+		}
+	}
+
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (d *directory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	res := &ldap.SearchResult{}
+
+	for _, e := range d.entries {
+		if req.Filter != fmt.Sprintf("(mail=%s)", ldap.EscapeFilter(e.mail)) {
+			continue
+		}
+
+		res.Entries = append(res.Entries, ldap.NewEntry(e.dn, map[string][]string{
+			"memberOf": e.groups,
+		}))
+	}
+
+	return res, nil
+}
+
+func (d *directory) Close() error {
+	d.closed++
+	return nil
+}
+
+type staticAuthenticator struct {
+	identity authSvc.Identity
+	err      error
+	calls    int
+}
+
+func (s *staticAuthenticator) Authenticate(ctx context.Context, email string, pass string) (authSvc.Identity, error) {
+	s.calls++
+	return s.identity, s.err
+}
+
+func newDirectory() *directory {
+	return &directory{
+		entries: []directoryEntry{
+			{
+				dn:     "uid=aibek,ou=staff,dc=inai,dc=kg",
+				mail:   "aibek@inai.kg",
+				pass:   "Secret1!",
+				groups: []string{staffGroup},
+			},
+			{
+				dn:     "uid=nurlan,ou=staff,dc=inai,dc=kg",
+				mail:   "nurlan@inai.kg",
+				pass:   "Secret2!",
+				groups: []string{staffGroup, adminGroup},
+			},
+		},
+	}
+}
+
+func newLDAPConfig() *config.LDAP {
+	return &config.LDAP{
+		Enabled:        true,
+		BindDN:         serviceDN,
+		BindPassword:   servicePass,
+		BaseDN:         "dc=inai,dc=kg",
+		UserFilter:     "(mail=%s)",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]int{adminGroup: 100},
+		DefaultLevel:   50,
+		Timeout:        time.Second,
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestLDAPAuthenticator_Authenticate(t *testing.T) {
+	dir := newDirectory()
+	authn := authSvc.NewLDAP(discardLogger(), newLDAPConfig(), dir.dial)
+
+	ctx := context.Background()
+
+	identity, err := authn.Authenticate(ctx, "aibek@inai.kg", "Secret1!")
+	require.NoError(t, err)
+	require.Equal(t, "aibek@inai.kg", identity.Email)
+	require.Equal(t, 50, identity.AccessLevel)
+	require.Equal(t, authSvc.SourceLDAP, identity.Source)
+
+	identity, err = authn.Authenticate(ctx, "nurlan@inai.kg", "Secret2!")
+	require.NoError(t, err)
+	require.Equal(t, 100, identity.AccessLevel)
+
+	require.Equal(t, []string{
+		serviceDN, "uid=aibek,ou=staff,dc=inai,dc=kg",
+		serviceDN, "uid=nurlan,ou=staff,dc=inai,dc=kg",
+	}, dir.binds)
+	require.Equal(t, 2, dir.closed)
+}
+
+func TestLDAPAuthenticator_Rejects(t *testing.T) {
+	dir := newDirectory()
+	authn := authSvc.NewLDAP(discardLogger(), newLDAPConfig(), dir.dial)
+
+	ctx := context.Background()
+
+	_, err := authn.Authenticate(ctx, "aibek@inai.kg", "wrong")
+	require.ErrorIs(t, err, authSvc.ErrInvalidCredentials)
+
+	_, err = authn.Authenticate(ctx, "aibek@inai.kg", "")
+	require.ErrorIs(t, err, authSvc.ErrInvalidCredentials)
+
+	_, err = authn.Authenticate(ctx, "student@inai.kg", "Secret1!")
+	require.ErrorIs(t, err, authSvc.ErrUnknownUser)
+
+	_, err = authn.Authenticate(ctx, "*)(mail=*", "Secret1!")
+	require.ErrorIs(t, err, authSvc.ErrUnknownUser)
+}
+
+func TestChainAuthenticator_FallsBackToLocal(t *testing.T) {
+	dir := newDirectory()
+	local := &staticAuthenticator{
+		identity: authSvc.Identity{Email: "student@inai.kg", AccessLevel: 50, Source: authSvc.SourceLocal},
+	}
+
+	authn := authSvc.NewChain(discardLogger(), authSvc.NewLDAP(discardLogger(), newLDAPConfig(), dir.dial), local)
+
+	identity, err := authn.Authenticate(context.Background(), "student@inai.kg", "Student1!")
+	require.NoError(t, err)
+	require.Equal(t, authSvc.SourceLocal, identity.Source)
+	require.Equal(t, 1, local.calls)
+}
+
+func TestChainAuthenticator_StopsOnInvalidCredentials(t *testing.T) {
+	dir := newDirectory()
+	local := &staticAuthenticator{
+		identity: authSvc.Identity{Email: "aibek@inai.kg", AccessLevel: 50, Source: authSvc.SourceLocal},
+	}
+
+	authn := authSvc.NewChain(discardLogger(), authSvc.NewLDAP(discardLogger(), newLDAPConfig(), dir.dial), local)
+
+	_, err := authn.Authenticate(context.Background(), "aibek@inai.kg", "wrong")
+	require.ErrorIs(t, err, authSvc.ErrInvalidCredentials)
+	require.Equal(t, 0, local.calls)
+}
+
+func TestChainAuthenticator_FallsBackWhenDirectoryIsDown(t *testing.T) {
+	local := &staticAuthenticator{
+		identity: authSvc.Identity{Email: "aibek@inai.kg", AccessLevel: 50, Source: authSvc.SourceLocal},
+	}
+
+	down := func(ctx context.Context) (authSvc.LDAPConn, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	authn := authSvc.NewChain(discardLogger(), authSvc.NewLDAP(discardLogger(), newLDAPConfig(), down), local)
+
+	identity, err := authn.Authenticate(context.Background(), "aibek@inai.kg", "Secret1!")
+	require.NoError(t, err)
+	require.Equal(t, authSvc.SourceLocal, identity.Source)
+}
@@ -0,0 +1,86 @@
+package auth_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	userDto "new-version/internal/contract/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	sessionRepo "new-version/internal/repository/session"
+	authSvc "new-version/internal/service/auth"
+	sessionSvc "new-version/internal/service/session"
+	"new-version/pkg/hasher"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var sessionColumns = []string{"id", "user_id", "user_agent", "ip", "created_at", "last_seen_at", "expires_at", "revoked_at"}
+
+func TestRequireSession(t *testing.T) {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	student := userDto.Model{Id: uuid.New(), Email: "aibek@inai.kg", AccessLevel: 50}
+	sessionId := uuid.New()
+
+	token, err := authSvc.New(nil, cfg, h).GenerateJwtToken(student, sessionId)
+	require.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	a := mwAuth.New(cfg.JwtSecret, sessionSvc.New(nil, sessionRepo.New(db), nil))
+	handler := a.Require(50)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	do := func() *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/user/sessions", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	query := regexp.QuoteMeta(`FROM sessions WHERE id = $1`)
+	tn := time.Now()
+
+	t.Run("revoked", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(sessionId).
+			WillReturnRows(mock.NewRows(sessionColumns).AddRow(sessionId, student.Id, "curl/8.0", "10.0.0.7", tn, tn, tn.Add(time.Hour), tn))
+
+		require.Equal(t, http.StatusUnauthorized, do().Code)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(sessionId).
+			WillReturnRows(mock.NewRows(sessionColumns))
+
+		require.Equal(t, http.StatusUnauthorized, do().Code)
+	})
+
+	t.Run("database down", func(t *testing.T) {
+		mock.ExpectQuery(query).
+			WithArgs(sessionId).
+			WillReturnError(errors.New("dial tcp 10.0.0.2:5432: i/o timeout"))
+
+		w := do()
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		require.NotContains(t, w.Body.String(), "i/o timeout")
+	})
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
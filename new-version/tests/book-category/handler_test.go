@@ -0,0 +1,207 @@
+package bookcategory_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	auditDto "new-version/internal/contract/audit"
+	bookCatDto "new-version/internal/contract/bookcategory"
+	userDto "new-version/internal/contract/user"
+	bookCatHdl "new-version/internal/http/handler/bookcategory"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCache "new-version/internal/http/middleware/cache"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+	mwRateLimit "new-version/internal/http/middleware/ratelimit"
+	"new-version/internal/http/router"
+	bookCatRepo "new-version/internal/repository/bookcategory"
+	authSvc "new-version/internal/service/auth"
+	bookCatSvc "new-version/internal/service/bookcategory"
+	"new-version/pkg/errs"
+	"new-version/pkg/hasher"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+// service keeps one category and applies writes naming its version.
+type service struct {
+	bc bookCatDto.Response
+}
+
+func (s *service) GetById(_ context.Context, id int) (bookCatDto.Response, error) {
+	if id != s.bc.Id {
+		return bookCatDto.Response{}, errs.NotFound("no book category with id = %d", id)
+	}
+
+	return s.bc, nil
+}
+
+func (s *service) UpdateById(_ context.Context, req bookCatDto.Request, _ int, version int) error {
+	if version != s.bc.Version {
+		return errs.Stale("book category has been changed")
+	}
+
+	s.bc.Title = req.Title
+	s.bc.Version++
+	return nil
+}
+
+func (s *service) DeleteById(_ context.Context, _ int, version int) error {
+	if version != s.bc.Version {
+		return errs.Stale("book category has been changed")
+	}
+
+	return nil
+}
+
+func (s *service) Create(context.Context, bookCatDto.Request) (int, error) { return 0, nil }
+
+func (s *service) GetByTitle(context.Context, string) (bookCatDto.Response, error) {
+	return s.bc, nil
+}
+
+func (s *service) GetList(context.Context) ([]bookCatDto.Response, error) {
+	return []bookCatDto.Response{s.bc}, nil
+}
+
+type noAudit struct{}
+
+func (noAudit) Record(context.Context, auditDto.Event) {}
+
+func (noAudit) List(context.Context, auditDto.Filter) ([]auditDto.Event, error) { return nil, nil }
+
+type activeSessions struct{}
+
+func (activeSessions) Validate(context.Context, uuid.UUID) error { return nil }
+
+// serve registers the handlers of svc under /api/v2 and returns a function
+// making admin requests to them.
+func serve(t *testing.T, svc bookCatSvc.Service) func(method string, path string, ifMatch string, body string) *httptest.ResponseRecorder {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	token, err := authSvc.New(nil, cfg, h).GenerateJwtToken(userDto.Model{Id: uuid.New(), Email: "admin@inai.kg", AccessLevel: 100}, uuid.New())
+	require.NoError(t, err)
+
+	mw := &router.Middlewares{
+		Auth:          mwAuth.New(cfg.JwtSecret, activeSessions{}),
+		Csrf:          mwCsrf.Csrf(cfg.JwtSecret),
+		RateLimit:     mwRateLimit.Limit(nil),
+		AuthRateLimit: mwRateLimit.Limit(nil),
+		CatalogCache:  mwCache.Control(mwCache.Public(0)),
+	}
+
+	rt := router.New()
+	bookCatHdl.New(nil, svc).RegisterRoutes(rt.Version(router.Version{Prefix: "/api/v2"}), mw)
+	handler := rt.Handler()
+
+	return func(method string, path string, ifMatch string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/v2"+path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			r.Header.Set("If-Match", ifMatch)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	svc := &service{bc: bookCatDto.Response{Id: 1, Title: "fantasy", UpdatedTime: time.Now(), Version: 1}}
+	serveOne := serve(t, svc)
+
+	do := func(method string, ifMatch string, body string) *httptest.ResponseRecorder {
+		return serveOne(method, "/book-category/1", ifMatch, body)
+	}
+
+	w := do(http.MethodGet, "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	first := w.Header().Get("ETag")
+	require.NotEmpty(t, first)
+
+	w = do(http.MethodPatch, "", `{"title": "mistery"}`)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+	require.Equal(t, "fantasy", svc.bc.Title)
+
+	w = do(http.MethodPatch, "*", `{"title": "mistery"}`)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+	require.Equal(t, "fantasy", svc.bc.Title)
+
+	w = do(http.MethodDelete, "*", "")
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w = do(http.MethodPatch, first, `{"title": "mistery"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	second := w.Header().Get("ETag")
+	require.NotEqual(t, first, second)
+	require.Equal(t, "mistery", svc.bc.Title)
+
+	// the second librarian still has the first version
+	w = do(http.MethodPatch, first, `{"title": "horror"}`)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+	require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	require.Equal(t, "mistery", svc.bc.Title)
+
+	w = do(http.MethodDelete, first, "")
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = do(http.MethodDelete, "W/"+second, "")
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = do(http.MethodDelete, second, "")
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestBadRequestDetail(t *testing.T) {
+	svc := &service{bc: bookCatDto.Response{Id: 1, Title: "fantasy", UpdatedTime: time.Now(), Version: 1}}
+	do := serve(t, svc)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		detail string
+	}{
+		{name: "wrong type", method: http.MethodPost, path: "/book-category/", body: `{"title": 1}`, detail: "invalid request body"},
+		{name: "broken json", method: http.MethodPatch, path: "/book-category/1", body: `{"title"`, detail: "invalid request body"},
+		{name: "id not a number", method: http.MethodGet, path: "/book-category/x", detail: "invalid id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.method, tt.path, `"v2-1-1"`, tt.body)
+			require.Equal(t, http.StatusBadRequest, w.Code)
+			require.Contains(t, w.Body.String(), `"detail":"`+tt.detail+`"`)
+			require.NotContains(t, w.Body.String(), "json:")
+			require.NotContains(t, w.Body.String(), "strconv")
+		})
+	}
+}
+
+func TestDuplicateTitle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	do := serve(t, bookCatSvc.New(nil, bookCatRepo.New(db), noAudit{}))
+
+	mock.ExpectQuery(`INSERT INTO book_categories`).
+		WithArgs("fantasy").
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "uq_book_categories_title"})
+
+	w := do(http.MethodPost, "/book-category/", "", `{"title": "fantasy"}`)
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -2,13 +2,16 @@ package bookcategory_test
 
 import (
 	"context"
+	"database/sql"
 	bookCatDto "new-version/internal/contract/bookcategory"
 	bookCatRepo "new-version/internal/repository/bookcategory"
+	"new-version/pkg/errs"
 	"regexp"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/require"
 )
 
@@ -52,9 +55,9 @@ func TestBookCategoryRepository_GetById(t *testing.T) {
 	id := 1
 	title := "fantasy"
 	tn := time.Now()
-	rows := mock.NewRows([]string{"id", "title", "created_time"}).AddRow(1, title, tn)
+	rows := mock.NewRows([]string{"id", "title", "created_at", "updated_at", "version"}).AddRow(1, title, tn, tn, 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM book_categories WHERE id = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, created_at, COALESCE(updated_at, created_at), version FROM book_categories WHERE id = $1`)).
 		WithArgs(id).
 		WillReturnRows(rows)
 
@@ -82,9 +85,9 @@ func TestBookCategoryRepository_GetByTitle(t *testing.T) {
 	id := 1
 	title := "fantasy"
 	tn := time.Now()
-	rows := mock.NewRows([]string{"id", "title", "created_time"}).AddRow(1, title, tn)
+	rows := mock.NewRows([]string{"id", "title", "created_at", "updated_at", "version"}).AddRow(1, title, tn, tn, 1)
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM book_categories WHERE title = $1`)).
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, created_at, COALESCE(updated_at, created_at), version FROM book_categories WHERE title = $1`)).
 		WithArgs(title).
 		WillReturnRows(rows)
 
@@ -112,14 +115,14 @@ func TestBookCategoryRepository_DeleteById(t *testing.T) {
 	tn := time.Now()
 	mock.NewRows([]string{"id", "title", "created_time"}).AddRow(id, title, tn)
 
-	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories WHERE id = $1`)).
-		WithArgs(id).
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories WHERE id = $1 AND version = $2`)).
+		WithArgs(id, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	repo := bookCatRepo.New(db)
 	ctx := context.Background()
 
-	err = repo.DeleteById(ctx, id)
+	err = repo.DeleteById(ctx, id, 1)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -146,15 +149,15 @@ func TestBookCategoryRepository_UpdateById(t *testing.T) {
 		Title: "mistery",
 	}
 
-	mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_categories SET title = $1 WHERE id = $2`)).
-		WithArgs(req.Title, id).
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_categories SET title = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND version = $3`)).
+		WithArgs(req.Title, id, 3).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	repo := bookCatRepo.New(db)
 
 	ctx := context.Background()
 
-	err = repo.UpdateById(ctx, req, id)
+	err = repo.UpdateById(ctx, req, id, 3)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -173,19 +176,19 @@ func TestBookCategoryRepository_GetList(t *testing.T) {
 
 	tn := time.Now()
 	bookCatList := []bookCatDto.Response{
-		{Id: 1, Title: "fantasy", CreatedTime: tn},
-		{Id: 2, Title: "mistery", CreatedTime: tn.Add(10 * time.Second)},
+		{Id: 1, Title: "fantasy", CreatedTime: tn, UpdatedTime: tn},
+		{Id: 2, Title: "mistery", CreatedTime: tn.Add(10 * time.Second), UpdatedTime: tn.Add(time.Hour), Version: 2},
 		{Id: 3, Title: "fiction", CreatedTime: tn.Add(20 * time.Second)},
 		{Id: 4, Title: "science", CreatedTime: tn.Add(30 * time.Second)},
 		{Id: 5, Title: "romance", CreatedTime: tn.Add(40 * time.Second)},
 	}
-	rows := mock.NewRows([]string{"id", "title", "created_time"})
+	rows := mock.NewRows([]string{"id", "title", "created_at", "updated_at", "version"})
 
 	for _, b := range bookCatList {
-		rows.AddRow(b.Id, b.Title, b.CreatedTime)
+		rows.AddRow(b.Id, b.Title, b.CreatedTime, b.UpdatedTime, b.Version)
 	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM book_categories`)).WillReturnRows(rows)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, created_at, COALESCE(updated_at, created_at), version FROM book_categories ORDER BY id`)).WillReturnRows(rows)
 
 	repo := bookCatRepo.New(db)
 
@@ -202,3 +205,82 @@ func TestBookCategoryRepository_GetList(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func TestBookCategoryRepository_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := bookCatRepo.New(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, title, created_at, COALESCE(updated_at, created_at), version FROM book_categories WHERE id = $1`)).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetById(ctx, 7)
+	require.ErrorIs(t, err, errs.ErrNotFound)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories WHERE id = $1 AND version = $2`)).
+		WithArgs(7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM book_categories WHERE id = $1)`)).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	require.ErrorIs(t, repo.DeleteById(ctx, 7, 1), errs.ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookCategoryRepository_Stale(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := bookCatRepo.New(db)
+	ctx := context.Background()
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_categories SET title = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND version = $3`)).
+		WithArgs("mistery", 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM book_categories WHERE id = $1)`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	err = repo.UpdateById(ctx, bookCatDto.Request{Title: "mistery"}, 1, 2)
+	require.ErrorIs(t, err, errs.ErrStale)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM book_categories WHERE id = $1 AND version = $2`)).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS (SELECT 1 FROM book_categories WHERE id = $1)`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	require.ErrorIs(t, repo.DeleteById(ctx, 1, 2), errs.ErrStale)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBookCategoryRepository_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := bookCatRepo.New(db)
+	ctx := context.Background()
+	duplicate := &pgconn.PgError{Code: "23505", ConstraintName: "uq_book_categories_title"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO book_categories(title) VALUES ($1) RETURNING id`)).
+		WithArgs("fantasy").
+		WillReturnError(duplicate)
+
+	_, err = repo.Create(ctx, bookCatDto.Request{Title: "fantasy"})
+	require.ErrorIs(t, err, errs.ErrConflict)
+
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE book_categories SET title = $1, version = version + 1, updated_at = CURRENT_TIMESTAMP WHERE id = $2 AND version = $3`)).
+		WithArgs("fantasy", 2, 1).
+		WillReturnError(duplicate)
+
+	require.ErrorIs(t, repo.UpdateById(ctx, bookCatDto.Request{Title: "fantasy"}, 2, 1), errs.ErrConflict)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
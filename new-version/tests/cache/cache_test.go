@@ -0,0 +1,123 @@
+package cache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mwCache "new-version/internal/http/middleware/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	updated := time.Date(2026, time.March, 1, 10, 0, 0, 0, time.UTC)
+
+	tag := mwCache.ETag("v2", 1, updated)
+	require.Regexp(t, `^"[0-9a-f]{32}"$`, tag)
+	require.Equal(t, tag, mwCache.ETag("v2", 1, updated.In(time.FixedZone("Bishkek", 6*60*60))))
+	require.NotEqual(t, tag, mwCache.ETag("v1", 1, updated))
+	require.NotEqual(t, tag, mwCache.ETag("v2", 1, updated.Add(time.Microsecond)))
+	require.NotEqual(t, mwCache.ETag("v2", 1, 23), mwCache.ETag("v2", 12, 3))
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2026, time.March, 1, 10, 0, 0, 500, time.UTC)
+	etag := mwCache.ETag("v2", 1, modified)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    bool
+	}{
+		{name: "no validators", method: http.MethodGet, want: false},
+		{name: "matching etag", method: http.MethodGet, headers: map[string]string{"If-None-Match": etag}, want: true},
+		{name: "one of the etags", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"other", W/` + etag}, want: true},
+		{name: "any", method: http.MethodHead, headers: map[string]string{"If-None-Match": "*"}, want: true},
+		{name: "stale etag", method: http.MethodGet, headers: map[string]string{"If-None-Match": `"other"`}, want: false},
+		{name: "not modified since", method: http.MethodGet, headers: map[string]string{"If-Modified-Since": "Sun, 01 Mar 2026 10:00:00 GMT"}, want: true},
+		{name: "modified since", method: http.MethodGet, headers: map[string]string{"If-Modified-Since": "Sun, 01 Mar 2026 09:59:59 GMT"}, want: false},
+		{name: "etag wins over date", method: http.MethodGet, headers: map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": "Sun, 01 Mar 2026 10:00:00 GMT",
+		}, want: false},
+		{name: "unsafe method", method: http.MethodPut, headers: map[string]string{"If-None-Match": etag}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/api/v2/book-category/1", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+
+			w := httptest.NewRecorder()
+			require.Equal(t, tt.want, mwCache.NotModified(w, r, etag, modified))
+			require.Equal(t, etag, w.Header().Get("ETag"))
+			require.Equal(t, "Sun, 01 Mar 2026 10:00:00 GMT", w.Header().Get("Last-Modified"))
+
+			if tt.want {
+				require.Equal(t, http.StatusNotModified, w.Code)
+			}
+		})
+	}
+}
+
+func TestControl(t *testing.T) {
+	status := http.StatusOK
+	h := mwCache.Control(mwCache.Public(time.Minute))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	for code, want := range map[int]string{
+		http.StatusOK:                  "public, max-age=60",
+		http.StatusNotModified:         "public, max-age=60",
+		http.StatusNotFound:            "no-store",
+		http.StatusInternalServerError: "no-store",
+	} {
+		status = code
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, code, w.Code)
+		require.Equal(t, want, w.Header().Get("Cache-Control"))
+	}
+
+	require.Equal(t, "public, no-cache", mwCache.Public(0))
+}
+
+func TestIfMatch(t *testing.T) {
+	etag := mwCache.ETag("v2", 1, 3)
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/v2/book-category/1", nil)
+	present, _ := mwCache.IfMatch(r, etag)
+	require.False(t, present)
+
+	// * matches any version, so it doesn't count as naming one
+	r.Header.Set("If-Match", "*")
+	present, _ = mwCache.IfMatch(r, etag)
+	require.False(t, present)
+	require.False(t, mwCache.HasIfMatch(r))
+
+	for header, want := range map[string]bool{
+		etag:                     true,
+		`"other", ` + etag:       true,
+		`*, "other"`:             false,
+		"W/" + etag:              false,
+		`"other"`:                false,
+		mwCache.ETag("v2", 1, 2): false,
+	} {
+		r.Header.Set("If-Match", header)
+
+		present, match := mwCache.IfMatch(r, etag)
+		require.True(t, present)
+		require.Equal(t, want, match, header)
+	}
+}
@@ -0,0 +1,232 @@
+package certs_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"new-version/internal/certs"
+	"new-version/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+type keyPair struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// issue signs a certificate for cn with parent, or self-signs it when parent
+// is nil.
+func issue(t *testing.T, cn string, parent *keyPair) *keyPair {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signer := &keyPair{cert: tmpl, key: key}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	} else {
+		signer = parent
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer.cert, &key.PublicKey, signer.key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &keyPair{cert: cert, key: key}
+}
+
+func (kp *keyPair) write(t *testing.T, dir string, name string) (certFile string, keyFile string) {
+	t.Helper()
+
+	keyDer, err := x509.MarshalPKCS8PrivateKey(kp.key)
+	require.NoError(t, err)
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: kp.cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDer}), 0o600))
+
+	return certFile, keyFile
+}
+
+func (kp *keyPair) tls() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{kp.cert.Raw}, PrivateKey: kp.key}
+}
+
+func discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	first := issue(t, "first", nil)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	r, err := certs.NewReloader(discard(), certFile, keyFile)
+	require.NoError(t, err)
+
+	cert, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, first.cert.Raw, cert.Certificate[0])
+
+	reloaded, err := r.Reload()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	second := issue(t, "second", nil)
+	second.write(t, dir, "server")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	reloaded, err = r.Reload()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+
+	cert, err = r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, second.cert.Raw, cert.Certificate[0])
+
+	// a broken file keeps the last good certificate
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	later = later.Add(time.Second)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	_, err = r.Reload()
+	require.Error(t, err)
+
+	cert, err = r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Equal(t, second.cert.Raw, cert.Certificate[0])
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := issue(t, "first", nil).write(t, dir, "server")
+
+	r, err := certs.NewReloader(discard(), certFile, keyFile)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		r.Watch(10 * time.Millisecond)(ctx)
+	}()
+
+	second := issue(t, "second", nil)
+	second.write(t, dir, "server")
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	require.Eventually(t, func() bool {
+		cert, _ := r.GetCertificate(nil)
+		return string(cert.Certificate[0]) == string(second.cert.Raw)
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := issue(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := issue(t, "admin", ca).write(t, dir, "admin")
+
+	r, err := certs.NewReloader(discard(), certFile, keyFile)
+	require.NoError(t, err)
+
+	tlsCfg, err := certs.MutualConfig(&config.TLS{MinVersion: "1.3"}, r, caFile)
+	require.NoError(t, err)
+
+	// StartTLS would add a certificate of its own, taking precedence over
+	// GetCertificate
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Listener = tls.NewListener(srv.Listener, tlsCfg)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.Start()
+	defer srv.Close()
+
+	url := "https://" + srv.Listener.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	client := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+	}
+
+	res, err := client(issue(t, "operator", ca).tls()).Get(url)
+	require.NoError(t, err)
+	res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	_, err = client().Get(url)
+	require.Error(t, err)
+
+	_, err = client(issue(t, "stranger", nil).tls()).Get(url)
+	require.Error(t, err)
+}
+
+func TestMutualConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := issue(t, "admin", nil).write(t, dir, "admin")
+
+	r, err := certs.NewReloader(discard(), certFile, keyFile)
+	require.NoError(t, err)
+
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, nil, 0o600))
+
+	_, err = certs.MutualConfig(&config.TLS{MinVersion: "1.2"}, r, empty)
+	require.ErrorIs(t, err, certs.ErrNoCertificates)
+
+	_, err = certs.MutualConfig(&config.TLS{MinVersion: "1.0"}, r, certFile)
+	require.Error(t, err)
+
+	_, err = certs.NewReloader(discard(), filepath.Join(dir, "missing.crt"), keyFile)
+	require.Error(t, err)
+}
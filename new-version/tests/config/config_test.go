@@ -0,0 +1,142 @@
+package config_test
+
+import (
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *config.Config {
+	return &config.Config{
+		Env: config.EnvProd,
+		HTTPServer: config.HTTPServer{
+			CORS: config.CORS{
+				AllowedOrigins:   []string{"https://library.example.com"},
+				AllowCredentials: true,
+			},
+			Cookie: config.Cookie{Secure: true, SameSite: "lax"},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, validConfig().Validate())
+
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Config)
+	}{
+		{name: "wildcard with credentials in prod", modify: func(cfg *config.Config) {
+			cfg.CORS.AllowedOrigins = []string{"https://library.example.com", "*"}
+		}},
+		{name: "no origins", modify: func(cfg *config.Config) {
+			cfg.CORS.AllowedOrigins = nil
+		}},
+		{name: "unknown same site", modify: func(cfg *config.Config) {
+			cfg.Cookie.SameSite = "sometimes"
+		}},
+		{name: "unknown rate limit group", modify: func(cfg *config.Config) {
+			cfg.RateLimit.Groups = map[string]config.Rate{"books": {PerMinute: 60, Burst: 10}}
+		}},
+		{name: "same site none without secure", modify: func(cfg *config.Config) {
+			cfg.Cookie.SameSite = "None"
+			cfg.Cookie.Secure = false
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			require.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestValidateWildcard(t *testing.T) {
+	cfg := validConfig()
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	require.ErrorIs(t, cfg.Validate(), config.ErrWildcardCredentials)
+
+	cfg.CORS.AllowCredentials = false
+	require.NoError(t, cfg.Validate())
+
+	cfg.CORS.AllowCredentials = true
+	cfg.Env = "local"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateTLS(t *testing.T) {
+	cfg := validConfig()
+	cfg.HTTPServer.TLS = config.TLS{Enabled: true, MinVersion: "1.3", ReloadInterval: time.Minute}
+	require.Error(t, cfg.Validate())
+
+	cfg.HTTPServer.TLS.CertFile = "server.crt"
+	cfg.HTTPServer.TLS.KeyFile = "server.key"
+	require.NoError(t, cfg.Validate())
+
+	cfg.HTTPServer.TLS.MinVersion = "1.1"
+	require.Error(t, cfg.Validate())
+
+	cfg = validConfig()
+	cfg.Admin.TLS = config.TLS{Enabled: true, CertFile: "admin.crt", KeyFile: "admin.key", MinVersion: "1.2", ReloadInterval: time.Minute}
+	require.Error(t, cfg.Validate())
+
+	cfg.Admin.ClientCAFile = "ca.crt"
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateAdminRequiresTLS(t *testing.T) {
+	cfg := validConfig()
+	cfg.Admin.Enabled = true
+	require.Error(t, cfg.Validate())
+
+	cfg.Env = config.EnvLocal
+	require.NoError(t, cfg.Validate())
+
+	cfg.Env = config.EnvProd
+	cfg.Admin.Enabled = false
+	require.NoError(t, cfg.Validate())
+}
+
+func TestValidateLifecycle(t *testing.T) {
+	cfg := validConfig()
+	cfg.API.Unversioned.Deprecated = time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, cfg.Validate())
+
+	cfg.API.Unversioned.Sunset = cfg.API.Unversioned.Deprecated.AddDate(0, 6, 0)
+	require.NoError(t, cfg.Validate())
+
+	cfg.API.Unversioned.Sunset = cfg.API.Unversioned.Deprecated.AddDate(0, -1, 0)
+	require.Error(t, cfg.Validate())
+}
+
+func TestMigratePasswordMinLen(t *testing.T) {
+	cfg := validConfig()
+	require.NoError(t, cfg.Migrate())
+	require.Equal(t, config.DefaultPasswordMinLen, cfg.PasswordPolicy.MinLen)
+
+	cfg = validConfig()
+	cfg.Security.PasswordMinLen = 12
+	require.NoError(t, cfg.Migrate())
+	require.Equal(t, 12, cfg.PasswordPolicy.MinLen)
+
+	cfg = validConfig()
+	cfg.Security.PasswordMinLen = 12
+	cfg.PasswordPolicy.MinLen = 10
+	require.Error(t, cfg.Migrate())
+}
+
+func TestRateLimitGroup(t *testing.T) {
+	rl := config.RateLimit{
+		UserPerMinute: 600,
+		UserBurst:     100,
+		Groups:        map[string]config.Rate{"audit": {PerMinute: 30, Burst: 5}},
+	}
+
+	require.Equal(t, config.Rate{PerMinute: 30, Burst: 5}, rl.Group("audit"))
+	require.Equal(t, config.Rate{PerMinute: 600, Burst: 100}, rl.Group("book_category"))
+}
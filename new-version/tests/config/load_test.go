@@ -0,0 +1,104 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+// load writes yaml after the settings every config needs and loads it.
+func load(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("env: local\nstorage_path: ./storage\n"+yaml), 0o600))
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestLoadPasswordPolicy(t *testing.T) {
+	policy := load(t, "").Security.PasswordPolicy
+	require.True(t, policy.RequireUpper)
+	require.True(t, policy.RequireLower)
+	require.True(t, policy.RequireDigit)
+	require.True(t, policy.RequireSpecial)
+	require.Equal(t, 3, policy.MaxRepeated)
+	require.True(t, policy.RejectEmailSimilar)
+
+	policy = load(t, `
+security:
+  password_policy:
+    require_upper: false
+    require_lower: false
+    require_digit: false
+    require_special: false
+    max_repeated: 0
+    reject_email_similar: false
+`).Security.PasswordPolicy
+	require.False(t, policy.RequireUpper)
+	require.False(t, policy.RequireLower)
+	require.False(t, policy.RequireDigit)
+	require.False(t, policy.RequireSpecial)
+	require.Zero(t, policy.MaxRepeated)
+	require.False(t, policy.RejectEmailSimilar)
+}
+
+func TestLoadAccessLogSampleRate(t *testing.T) {
+	require.Equal(t, 1.0, load(t, "").HTTPServer.AccessLog.SampleRate)
+	require.Zero(t, load(t, "http_server:\n  access_log:\n    sample_rate: 0\n").HTTPServer.AccessLog.SampleRate)
+}
+
+func TestLoadAdmin(t *testing.T) {
+	require.False(t, load(t, "").Admin.Enabled)
+	require.True(t, load(t, "admin:\n  enabled: true\n").Admin.Enabled)
+}
+
+func TestLoadRateLimit(t *testing.T) {
+	require.True(t, load(t, "").RateLimit.Enabled)
+	require.False(t, load(t, "rate_limit:\n  enabled: false\n").RateLimit.Enabled)
+}
+
+func TestLoadCredentials(t *testing.T) {
+	cfg := load(t, "")
+	require.True(t, cfg.CORS.AllowCredentials)
+	require.True(t, cfg.Cookie.Secure)
+
+	cfg = load(t, `
+http_server:
+  cors:
+    allow_credentials: false
+  cookie:
+    secure: false
+`)
+	require.False(t, cfg.CORS.AllowCredentials)
+	require.False(t, cfg.Cookie.Secure)
+}
+
+func TestLoadAPI(t *testing.T) {
+	cfg := load(t, "")
+	require.True(t, cfg.API.Unversioned.Enabled)
+	require.True(t, cfg.API.V1.Enabled)
+
+	cfg = load(t, `
+api:
+  unversioned:
+    enabled: false
+  v1:
+    enabled: false
+`)
+	require.False(t, cfg.API.Unversioned.Enabled)
+	require.False(t, cfg.API.V1.Enabled)
+}
+
+func TestLoadCatalogMaxAge(t *testing.T) {
+	require.Equal(t, time.Minute, load(t, "").Cache.CatalogMaxAge)
+	require.Zero(t, load(t, "http_server:\n  cache:\n    catalog_max_age: 0s\n").Cache.CatalogMaxAge)
+}
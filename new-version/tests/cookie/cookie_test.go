@@ -0,0 +1,57 @@
+package cookie_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	"new-version/internal/http/cookie"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSet(t *testing.T) {
+	jar, err := cookie.New(&config.Cookie{Domain: "example.com", Secure: true, SameSite: "strict"})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	jar.Set(w, "access_token", "token", time.Hour)
+
+	c := w.Result().Cookies()[0]
+	require.Equal(t, "token", c.Value)
+	require.Equal(t, "example.com", c.Domain)
+	require.True(t, c.Secure)
+	require.True(t, c.HttpOnly)
+	require.Equal(t, http.SameSiteStrictMode, c.SameSite)
+	require.Equal(t, 3600, c.MaxAge)
+
+	w = httptest.NewRecorder()
+	jar.Set(w, "csrf_token", "token", 0)
+	require.Zero(t, w.Result().Cookies()[0].MaxAge)
+
+	w = httptest.NewRecorder()
+	jar.Clear(w, "access_token")
+	c = w.Result().Cookies()[0]
+	require.Empty(t, c.Value)
+	require.Negative(t, c.MaxAge)
+}
+
+func TestConfiguredMaxAge(t *testing.T) {
+	jar, err := cookie.New(&config.Cookie{SameSite: "lax", MaxAge: 10 * time.Minute})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	jar.Set(w, "access_token", "token", time.Hour)
+
+	c := w.Result().Cookies()[0]
+	require.Equal(t, 600, c.MaxAge)
+	require.False(t, c.Secure)
+	require.Equal(t, http.SameSiteLaxMode, c.SameSite)
+}
+
+func TestUnknownSameSite(t *testing.T) {
+	_, err := cookie.New(&config.Cookie{SameSite: "loose"})
+	require.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package csrf_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "test-secret"
+
+func TestToken(t *testing.T) {
+	session := uuid.New()
+
+	token, err := mwCsrf.NewToken(secret, session)
+	require.NoError(t, err)
+
+	require.True(t, mwCsrf.ValidToken(secret, session, token))
+	require.False(t, mwCsrf.ValidToken("other-secret", session, token))
+	require.False(t, mwCsrf.ValidToken(secret, uuid.New(), token))
+	require.False(t, mwCsrf.ValidToken(secret, uuid.Nil, token))
+	require.False(t, mwCsrf.ValidToken(secret, session, token+"x"))
+	require.False(t, mwCsrf.ValidToken(secret, session, ""))
+}
+
+func TestCsrf(t *testing.T) {
+	session := uuid.New()
+
+	token, err := mwCsrf.NewToken(secret, session)
+	require.NoError(t, err)
+
+	forged, err := mwCsrf.NewToken("attacker-secret", session)
+	require.NoError(t, err)
+
+	// issued to the attacker's own login
+	foreign, err := mwCsrf.NewToken(secret, uuid.New())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		method string
+		bearer bool
+		access bool
+		cookie string
+		header string
+		ok     bool
+	}{
+		{name: "safe method", method: http.MethodGet, access: true, ok: true},
+		{name: "anonymous", method: http.MethodPost, ok: true},
+		{name: "bearer token", method: http.MethodDelete, bearer: true, access: true, ok: true},
+		{name: "cookie without token", method: http.MethodPost, access: true},
+		{name: "header missing", method: http.MethodPatch, access: true, cookie: token},
+		{name: "header mismatch", method: http.MethodPatch, access: true, cookie: token, header: forged},
+		{name: "unsigned token", method: http.MethodDelete, access: true, cookie: forged, header: forged},
+		{name: "token of another session", method: http.MethodDelete, access: true, cookie: foreign, header: foreign},
+		{name: "valid token", method: http.MethodDelete, access: true, cookie: token, header: token, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/book-category/1", nil)
+			if tt.access {
+				r.AddCookie(&http.Cookie{Name: "access_token", Value: "jwt"})
+			}
+			if tt.bearer {
+				r.Header.Set("Authorization", "Bearer jwt")
+			}
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: mwCsrf.CookieName, Value: tt.cookie})
+			}
+			if tt.header != "" {
+				r.Header.Set(mwCsrf.HeaderName, tt.header)
+			}
+
+			r = r.WithContext(mwAuth.WithPrincipal(r.Context(), mwAuth.Principal{SessionId: session}))
+
+			called := false
+			next := http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true })
+
+			w := httptest.NewRecorder()
+			mwCsrf.Csrf(secret)(next).ServeHTTP(w, r)
+
+			require.Equal(t, tt.ok, called)
+			if !tt.ok {
+				require.Equal(t, http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
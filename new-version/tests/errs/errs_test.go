@@ -0,0 +1,80 @@
+package errs_test
+
+import (
+	stdJson "encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"new-version/pkg/errs"
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusFromError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: errs.NotFound("no book category with id = %d", 3), want: http.StatusNotFound},
+		{err: errs.Conflict("already exists"), want: http.StatusConflict},
+		{err: errs.Validation("title is required"), want: http.StatusUnprocessableEntity},
+		{err: errs.Forbidden("not yours"), want: http.StatusForbidden},
+		{err: errs.Unauthorized("invalid credentials"), want: http.StatusUnauthorized},
+		{err: errs.Stale("book category has been changed"), want: http.StatusPreconditionFailed},
+		{err: errors.New("connection refused"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		wrapped := fmt.Errorf("service.op: %w", fmt.Errorf("repository.op: %w", tt.err))
+		require.Equal(t, tt.want, hp.StatusFromError(wrapped), tt.err.Error())
+	}
+}
+
+func TestWriteErrorFrom(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/book-category/3", nil)
+	r = r.WithContext(hp.WithRequestID(r.Context(), "req-1"))
+
+	w := httptest.NewRecorder()
+	json.WriteErrorFrom(w, r, fmt.Errorf("modules.bookcategory.repository.GetById: %w", errs.NotFound("no book category with id = 3")))
+
+	var problem hp.Problem
+	require.NoError(t, stdJson.NewDecoder(w.Body).Decode(&problem))
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	require.Equal(t, hp.Problem{
+		Type:      "urn:inai-library:problem:not-found",
+		Title:     "Not Found",
+		Status:    http.StatusNotFound,
+		Detail:    "no book category with id = 3",
+		Instance:  "/book-category/3",
+		RequestID: "req-1",
+	}, problem)
+
+	w = httptest.NewRecorder()
+	json.WriteErrorFrom(w, r, errors.New("modules.user.repository.Create: password authentication failed"))
+
+	problem = hp.Problem{}
+	require.NoError(t, stdJson.NewDecoder(w.Body).Decode(&problem))
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Empty(t, problem.Detail)
+	require.Equal(t, "req-1", problem.RequestID)
+}
+
+func TestWriteErrorFrom_Fields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/book-category/", nil)
+	w := httptest.NewRecorder()
+
+	fields := []errs.FieldError{{Field: "title", Message: "is required"}}
+	json.WriteErrorFrom(w, r, errs.InvalidFields(fields))
+
+	var problem hp.Problem
+	require.NoError(t, stdJson.NewDecoder(w.Body).Decode(&problem))
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Equal(t, "urn:inai-library:problem:validation", problem.Type)
+	require.Equal(t, fields, problem.Errors)
+}
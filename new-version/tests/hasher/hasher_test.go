@@ -0,0 +1,131 @@
+package hasher_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"new-version/pkg/hasher"
+
+	"github.com/stretchr/testify/require"
+)
+
+var argon2Params = hasher.Argon2Params{
+	Memory:      8 * 1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func TestBcrypt(t *testing.T) {
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("Secret1!")
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^\$2a\$04\$`), encoded)
+
+	ok, err := h.Verify(encoded, "Secret1!")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = h.Verify(encoded, "secret1!")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.False(t, h.NeedsRehash(encoded))
+
+	stronger, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 5})
+	require.NoError(t, err)
+	require.True(t, stronger.NeedsRehash(encoded))
+}
+
+func TestArgon2id(t *testing.T) {
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgArgon2id, Argon2: argon2Params})
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("Secret1!")
+	require.NoError(t, err)
+	require.Regexp(t, regexp.MustCompile(`^\$argon2id\$v=19\$m=8192,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), encoded)
+
+	ok, err := h.Verify(encoded, "Secret1!")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = h.Verify(encoded, "Secret2!")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.False(t, h.NeedsRehash(encoded))
+
+	tuned := argon2Params
+	tuned.Iterations = 2
+
+	stronger, err := hasher.New(hasher.Options{Algorithm: hasher.AlgArgon2id, Argon2: tuned})
+	require.NoError(t, err)
+	require.True(t, stronger.NeedsRehash(encoded))
+
+	// old hashes keep verifying after the parameters change
+	ok, err = stronger.Verify(encoded, "Secret1!")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
+
+func TestMigrationBetweenAlgorithms(t *testing.T) {
+	bc, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	argon, err := hasher.New(hasher.Options{Algorithm: hasher.AlgArgon2id, Argon2: argon2Params})
+	require.NoError(t, err)
+
+	legacy, err := bc.Hash("Secret1!")
+	require.NoError(t, err)
+
+	ok, err := argon.Verify(legacy, "Secret1!")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, argon.NeedsRehash(legacy))
+
+	upgraded, err := argon.Hash("Secret1!")
+	require.NoError(t, err)
+	require.True(t, bc.NeedsRehash(upgraded))
+}
+
+func TestInvalidInput(t *testing.T) {
+	h, err := hasher.New(hasher.Options{})
+	require.NoError(t, err)
+
+	_, err = h.Verify("plaintext", "plaintext")
+	require.ErrorIs(t, err, hasher.ErrUnknownFormat)
+
+	_, err = h.Hash(strings.Repeat("кд", 20))
+	require.ErrorIs(t, err, hasher.ErrPasswordTooLong)
+
+	_, err = hasher.New(hasher.Options{Algorithm: "md5"})
+	require.Error(t, err)
+
+	_, err = hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 40})
+	require.Error(t, err)
+
+	_, err = hasher.New(hasher.Options{Algorithm: hasher.AlgArgon2id})
+	require.Error(t, err)
+}
+
+func TestZeroedArgon2idParameters(t *testing.T) {
+	h, err := hasher.New(hasher.Options{})
+	require.NoError(t, err)
+
+	salt := "c2FsdHNhbHRzYWx0c2FsdA"
+	key := "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"
+
+	for _, params := range []string{"m=65536,t=0,p=2", "m=65536,t=1,p=0", "m=8,t=1,p=4", "m=0,t=0,p=0"} {
+		encoded := "$argon2id$v=19$" + params + "$" + salt + "$" + key
+
+		require.NotPanics(t, func() {
+			ok, err := h.Verify(encoded, "password")
+			require.Error(t, err, params)
+			require.False(t, ok)
+		})
+	}
+}
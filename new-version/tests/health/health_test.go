@@ -0,0 +1,80 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+
+	healthDto "new-version/internal/contract/health"
+	schemaRepo "new-version/internal/repository/schema"
+	healthSvc "new-version/internal/service/health"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+func newService(t *testing.T, storagePath string) (*healthSvc.DefaultService, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	return healthSvc.New(log, db, schemaRepo.New(db), 3, storagePath), mock
+}
+
+func statuses(report healthDto.Report) map[string]string {
+	m := map[string]string{}
+	for _, c := range report.Checks {
+		m[c.Name] = c.Status
+	}
+
+	return m
+}
+
+func TestReady(t *testing.T) {
+	svc, mock := newService(t, t.TempDir())
+
+	mock.ExpectPing()
+	mock.ExpectQuery(`SELECT version FROM schema_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	report := svc.Ready(context.Background())
+
+	require.True(t, report.OK())
+	require.Equal(t, map[string]string{"database": "ok", "schema": "ok", "storage": "ok"}, statuses(report))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestNotReady(t *testing.T) {
+	svc, mock := newService(t, filepath.Join(t.TempDir(), "missing"))
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectQuery(`SELECT version FROM schema_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+
+	report := svc.Ready(context.Background())
+
+	require.False(t, report.OK())
+	require.Equal(t, map[string]string{"database": "unavailable", "schema": "unavailable", "storage": "unavailable"}, statuses(report))
+
+	for _, c := range report.Checks {
+		require.NotContains(t, c.Error, "connection refused", "internal errors must not be exposed")
+	}
+	require.Equal(t, "schema version is 2, expected 3", report.Checks[1].Error)
+}
+
+func TestVersion(t *testing.T) {
+	svc, mock := newService(t, t.TempDir())
+
+	mock.ExpectQuery(`SELECT version FROM schema_version`).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	v := svc.Version(context.Background())
+
+	require.Equal(t, 3, v.SchemaVersion)
+	require.NotEmpty(t, v.GoVersion)
+}
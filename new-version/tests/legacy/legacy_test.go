@@ -0,0 +1,300 @@
+package legacy_test
+
+import (
+	"context"
+	stdJson "encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	bookCatDto "new-version/internal/contract/bookcategory"
+	sessionDto "new-version/internal/contract/session"
+	userDto "new-version/internal/contract/user"
+	legacyHdl "new-version/internal/http/handler/legacy"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCache "new-version/internal/http/middleware/cache"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+	mwRateLimit "new-version/internal/http/middleware/ratelimit"
+	"new-version/internal/http/router"
+	authSvc "new-version/internal/service/auth"
+	"new-version/pkg/errs"
+	"new-version/pkg/hasher"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type users struct {
+	jwt      *authSvc.JwtService
+	accounts map[string]userDto.Model
+}
+
+func (u *users) Register(_ context.Context, req userDto.Request) error {
+	if _, ok := u.accounts[req.Email]; ok {
+		return errs.Conflict("user %s already exists", req.Email)
+	}
+
+	u.accounts[req.Email] = userDto.Model{Id: uuid.New(), Email: req.Email, Password: req.Password, AccessLevel: 50}
+	return nil
+}
+
+func (u *users) Login(_ context.Context, req userDto.Request) (string, error) {
+	m, ok := u.accounts[req.Email]
+	if !ok || m.Password != req.Password {
+		return "", errs.Unauthorized("invalid credentials")
+	}
+
+	return u.jwt.GenerateJwtToken(m, uuid.New())
+}
+
+type sessions struct {
+	revoked []uuid.UUID
+}
+
+func (s *sessions) Create(context.Context, uuid.UUID, time.Duration) (uuid.UUID, error) {
+	return uuid.New(), nil
+}
+
+func (s *sessions) List(context.Context, uuid.UUID, uuid.UUID) ([]sessionDto.Response, error) {
+	return nil, nil
+}
+
+func (s *sessions) Revoke(_ context.Context, _ uuid.UUID, id uuid.UUID) error {
+	s.revoked = append(s.revoked, id)
+	return nil
+}
+
+func (s *sessions) RevokeAll(context.Context, uuid.UUID) error { return nil }
+
+func (s *sessions) Validate(context.Context, uuid.UUID) error { return nil }
+
+type categories struct {
+	list []bookCatDto.Response
+}
+
+func (c *categories) GetById(_ context.Context, id int) (bookCatDto.Response, error) {
+	for _, bc := range c.list {
+		if bc.Id == id {
+			return bc, nil
+		}
+	}
+
+	return bookCatDto.Response{}, errs.NotFound("no book category with id = %d", id)
+}
+
+func (c *categories) Create(_ context.Context, req bookCatDto.Request) (int, error) {
+	id := len(c.list) + 1
+	c.list = append(c.list, bookCatDto.Response{Id: id, Title: req.Title, Version: 1})
+	return id, nil
+}
+
+func (c *categories) DeleteById(_ context.Context, id int, version int) error {
+	return c.UpdateById(context.Background(), bookCatDto.Request{}, id, version)
+}
+
+func (c *categories) UpdateById(_ context.Context, req bookCatDto.Request, id int, version int) error {
+	for i, bc := range c.list {
+		if bc.Id != id {
+			continue
+		}
+
+		if bc.Version != version {
+			return errs.Stale("book category with id = %d has been changed", id)
+		}
+
+		c.list[i].Title = req.Title
+		c.list[i].Version++
+		return nil
+	}
+
+	return errs.NotFound("no book category with id = %d", id)
+}
+
+func (c *categories) GetByTitle(context.Context, string) (bookCatDto.Response, error) {
+	return bookCatDto.Response{}, nil
+}
+
+func (c *categories) GetList(context.Context) ([]bookCatDto.Response, error) {
+	return c.list, nil
+}
+
+type fixture struct {
+	handler  http.Handler
+	sessions *sessions
+	users    *users
+}
+
+func setup(t *testing.T) *fixture {
+	t.Helper()
+
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	s := &sessions{}
+	mw := &router.Middlewares{
+		Auth:          mwAuth.New(cfg.JwtSecret, s),
+		Csrf:          mwCsrf.Csrf(cfg.JwtSecret),
+		RateLimit:     mwRateLimit.Limit(nil),
+		AuthRateLimit: mwRateLimit.Limit(nil),
+		CatalogCache:  mwCache.Control(mwCache.Public(time.Minute)),
+	}
+
+	u := &users{jwt: authSvc.New(nil, cfg, h), accounts: map[string]userDto.Model{}}
+	c := &categories{list: []bookCatDto.Response{{Id: 1, Title: "Fiction", CreatedTime: time.Now(), Version: 1}}}
+
+	rt := router.New()
+	sunset := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	legacyHdl.New(slog.New(slog.DiscardHandler), u, s, c, cfg).RegisterRoutes(rt.Version(router.Version{Prefix: "/api/v1", Sunset: sunset}), mw)
+
+	return &fixture{handler: rt.Handler(), sessions: s, users: u}
+}
+
+func (f *fixture) do(method string, path string, body string, token string) (*httptest.ResponseRecorder, map[string]any) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+
+	var resp map[string]any
+	_ = stdJson.Unmarshal(w.Body.Bytes(), &resp)
+
+	return w, resp
+}
+
+func TestAuth(t *testing.T) {
+	f := setup(t)
+	credentials := `{"email": "aibek@inai.kg", "password": "Secret-123", "firstname": "Aibek"}`
+
+	w, resp := f.do(http.MethodPost, "/api/v1/register", credentials, "")
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Equal(t, "Пользователь успешно зарегистрирован", resp["Сообщение"])
+	require.NotEmpty(t, resp["access_token"])
+	require.Equal(t, resp["access_token"], resp["refresh_token"])
+	require.Equal(t, "aibek@inai.kg", resp["user"].(map[string]any)["email"])
+	require.Equal(t, "Student", resp["user"].(map[string]any)["role"])
+	require.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", w.Header().Get("Sunset"))
+
+	w, resp = f.do(http.MethodPost, "/api/v1/register", credentials, "")
+	require.Equal(t, http.StatusConflict, w.Code)
+	require.Equal(t, "user aibek@inai.kg already exists", resp["Сообщение"])
+
+	w, resp = f.do(http.MethodPost, "/api/v1/login", `{"email": "aibek@inai.kg", "password": "wrong"}`, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "Неверный email или пароль", resp["Сообщение"])
+
+	w, resp = f.do(http.MethodPost, "/api/v1/login", `{"email": "aibek@inai.kg"}`, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NotEmpty(t, resp["Сообщение"])
+
+	w, resp = f.do(http.MethodPost, "/api/v1/login", `{"email": 1}`, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "Неправильный запрос.", resp["Сообщение"])
+
+	w, resp = f.do(http.MethodPost, "/api/v1/login", credentials, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "Пользователь успешно вошел в систему", resp["Сообщение"])
+
+	token := resp["refresh_token"].(string)
+
+	w, resp = f.do(http.MethodPost, "/api/v1/logout", `{}`, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "Отсутствует Refresh токен", resp["Сообщение"])
+
+	w, _ = f.do(http.MethodPost, "/api/v1/logout", `{"refresh_token": "forged"}`, "")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w, _ = f.do(http.MethodPost, "/api/v1/logout", `{"refresh_token": "`+token+`"}`, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Len(t, f.sessions.revoked, 1)
+}
+
+func TestCategories(t *testing.T) {
+	f := setup(t)
+
+	w, _ := f.do(http.MethodGet, "/api/v1/category/all", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[{"id": 1, "title": "Fiction", "version": 1}]`, w.Body.String())
+
+	w, _ = f.do(http.MethodGet, "/api/v1/category/1", "", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id": 1, "title": "Fiction", "version": 1}`, w.Body.String())
+	require.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	require.NotEmpty(t, w.Header().Get("ETag"))
+
+	w, resp := f.do(http.MethodGet, "/api/v1/category/7", "", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "no book category with id = 7", resp["Сообщение"])
+
+	w, _ = f.do(http.MethodGet, "/api/v1/category/fiction", "", "")
+	require.Equal(t, http.StatusNotFound, w.Code)
+
+	w, _ = f.do(http.MethodPost, "/api/v1/category/create", `{"title": "Poetry"}`, "")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	_, resp = f.do(http.MethodPost, "/api/v1/register", `{"email": "aibek@inai.kg", "password": "Secret-123"}`, "")
+	token := resp["access_token"].(string)
+
+	w, _ = f.do(http.MethodPost, "/api/v1/category/create", `{"title": "Poetry"}`, token)
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.JSONEq(t, `{"id": 2, "title": "Poetry", "version": 1}`, w.Body.String())
+
+	w, _ = f.do(http.MethodDelete, "/api/v1/category/2", "", token)
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCategoryVersion(t *testing.T) {
+	f := setup(t)
+
+	f.users.accounts["admin@inai.kg"] = userDto.Model{Id: uuid.New(), Email: "admin@inai.kg", Password: "Secret-123", AccessLevel: 100}
+	_, resp := f.do(http.MethodPost, "/api/v1/login", `{"email": "admin@inai.kg", "password": "Secret-123"}`, "")
+	token := resp["access_token"].(string)
+
+	// a blind write would overwrite whatever was saved since the client read
+	w, resp := f.do(http.MethodPut, "/api/v1/category/1", `{"title": "Novels"}`, token)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+	require.NotEmpty(t, resp["Сообщение"])
+
+	// * matches any version, so it's no better than a blind write
+	r := httptest.NewRequest(http.MethodPut, "/api/v1/category/1", strings.NewReader(`{"title": "Novels"}`))
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set("If-Match", "*")
+	w = httptest.NewRecorder()
+	f.handler.ServeHTTP(w, r)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w, _ = f.do(http.MethodPut, "/api/v1/category/1", `{"title": "Novels", "version": 1}`, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"id": 1, "title": "Novels", "version": 2}`, w.Body.String())
+
+	// the second librarian still has version 1
+	w, _ = f.do(http.MethodPatch, "/api/v1/category/1", `{"title": "Prose", "version": 1}`, token)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w, _ = f.do(http.MethodDelete, "/api/v1/category/1", "", token)
+	require.Equal(t, http.StatusPreconditionRequired, w.Code)
+
+	w, _ = f.do(http.MethodDelete, "/api/v1/category/1?version=1", "", token)
+	require.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w, _ = f.do(http.MethodDelete, "/api/v1/category/1?version=2", "", token)
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestNotPorted(t *testing.T) {
+	f := setup(t)
+
+	for _, path := range []string{"/api/v1/book/all", "/api/v1/order/create", "/api/v1/user/3"} {
+		w, resp := f.do(http.MethodGet, path, "", "")
+		require.Equal(t, http.StatusNotImplemented, w.Code, path)
+		require.Equal(t, "Раздел пока недоступен", resp["Сообщение"])
+	}
+}
@@ -0,0 +1,82 @@
+package logger_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	mwLog "new-version/internal/http/middleware/logger"
+	"new-version/internal/http/router"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newAccessRouter(cfg *config.AccessLog) (http.Handler, func(t *testing.T) map[string]any, func() int) {
+	log, buf := newLogger()
+
+	rt := router.New()
+	rt.Use(mwLog.Logger(log, cfg))
+
+	rt.HandleFunc("POST /books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		mwLog.SetUser(r.Context(), "user-1")
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body[:2])
+	})
+	rt.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	rt.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	last := func(t *testing.T) map[string]any { return lastRecord(t, buf) }
+	count := func() int { return strings.Count(buf.String(), "\n") }
+
+	return rt.Handler(), last, count
+}
+
+func TestAccessLog(t *testing.T) {
+	h, last, _ := newAccessRouter(&config.AccessLog{SampleRate: 1})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/books/7", strings.NewReader("hello")))
+
+	rec := last(t)
+	require.Equal(t, "INFO", rec["level"])
+	require.Equal(t, "POST /books/{id}", rec["route"])
+	require.Equal(t, "/books/7", rec["path"])
+	require.EqualValues(t, http.StatusCreated, rec["status"])
+	require.Equal(t, "user-1", rec["user_id"])
+	require.EqualValues(t, 5, rec["bytes_in"])
+	require.EqualValues(t, 2, rec["bytes_out"])
+	require.Contains(t, rec, "latency_ms")
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	rec = last(t)
+	require.Equal(t, "WARN", rec["level"])
+	require.Equal(t, "unmatched", rec["route"])
+	require.EqualValues(t, http.StatusNotFound, rec["status"])
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	h, last, count := newAccessRouter(&config.AccessLog{SampleRate: 0, SlowThreshold: 10 * time.Millisecond})
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/books/7", strings.NewReader("hello")))
+	require.Zero(t, count())
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	require.Equal(t, 1, count())
+	require.Equal(t, "ERROR", last(t)["level"])
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	require.Equal(t, 2, count())
+
+	rec := last(t)
+	require.Equal(t, "WARN", rec["level"])
+	require.Equal(t, "slow request", rec["msg"])
+}
@@ -0,0 +1,95 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mwRequestId "new-version/internal/http/middleware/requestid"
+	hp "new-version/pkg/httphelpers"
+	"new-version/pkg/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newLogger() (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(logger.NewContextHandler(slog.NewJSONHandler(&buf, nil))), &buf
+}
+
+func lastRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &rec))
+
+	return rec
+}
+
+func TestContextHandler(t *testing.T) {
+	log, buf := newLogger()
+
+	ctx := logger.WithAttrs(context.Background(), slog.String(logger.KeyRequestID, "req-1"))
+	userCtx := logger.WithAttrs(ctx, slog.String(logger.KeyUserID, "user-1"))
+
+	log.With(slog.String("op", "test")).InfoContext(userCtx, "hello")
+	rec := lastRecord(t, buf)
+	require.Equal(t, "req-1", rec[logger.KeyRequestID])
+	require.Equal(t, "user-1", rec[logger.KeyUserID])
+	require.Equal(t, "test", rec["op"])
+
+	// the parent context isn't affected by attrs added to a child
+	log.InfoContext(ctx, "hello")
+	rec = lastRecord(t, buf)
+	require.Equal(t, "req-1", rec[logger.KeyRequestID])
+	require.NotContains(t, rec, logger.KeyUserID)
+
+	log.Info("hello")
+	require.NotContains(t, lastRecord(t, buf), logger.KeyRequestID)
+}
+
+func TestRequestID(t *testing.T) {
+	log, buf := newLogger()
+
+	handler := mwRequestId.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.InfoContext(r.Context(), "handled")
+	}))
+
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{name: "generated"},
+		{name: "incoming", incoming: "b7f3c2a0-trace.42", kept: true},
+		{name: "unsafe", incoming: "id\nforged=1"},
+		{name: "too long", incoming: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				r.Header.Set(hp.HeaderRequestID, tt.incoming)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get(hp.HeaderRequestID)
+			require.NotEmpty(t, id)
+			if tt.kept {
+				require.Equal(t, tt.incoming, id)
+			} else {
+				require.NotEqual(t, tt.incoming, id)
+			}
+
+			require.Equal(t, id, lastRecord(t, buf)[logger.KeyRequestID])
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"new-version/internal/http/router"
+	"new-version/internal/metrics"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+type overdue int
+
+func (o overdue) CountOverdue(context.Context) (int, error) { return int(o), nil }
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	return w.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	m := metrics.New(log, db, overdue(3))
+
+	rt := router.New()
+	rt.Use(m.Middleware)
+	rt.HandleFunc("GET /books/{id}", func(http.ResponseWriter, *http.Request) {})
+
+	h := rt.Handler()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/books/1", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/books/2", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+	m.Registered()
+	m.LoggedIn(nil)
+	m.LoggedIn(errors.New("invalid credentials"))
+
+	body := scrape(t, m)
+
+	for _, line := range []string{
+		`library_http_requests_total{method="GET",route="GET /books/{id}",status="200"} 2`,
+		`library_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`library_http_request_duration_seconds_count{method="GET",route="GET /books/{id}",status="200"} 2`,
+		`library_registrations_total 1`,
+		`library_logins_total{outcome="success"} 1`,
+		`library_logins_total{outcome="failure"} 1`,
+		`library_overdue_loans 3`,
+		`go_sql_max_open_connections{db_name="postgres"}`,
+		`go_goroutines`,
+	} {
+		require.Contains(t, body, line)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *metrics.Metrics
+
+	require.NotPanics(t, func() {
+		m.Registered()
+		m.LoggedIn(nil)
+	})
+}
@@ -0,0 +1,175 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	userDto "new-version/internal/contract/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwRateLimit "new-version/internal/http/middleware/ratelimit"
+	mwRealIp "new-version/internal/http/middleware/realip"
+	authSvc "new-version/internal/service/auth"
+	"new-version/pkg/hasher"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var ok = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+
+func request(remoteAddr string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/user/login", nil)
+	r.RemoteAddr = remoteAddr
+	return r
+}
+
+func TestLimit(t *testing.T) {
+	h := mwRateLimit.Limit(mwRateLimit.New(1, 2, 0))(ok)
+
+	for i, remaining := range []int{1, 0} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request("10.0.0.1:5000"))
+
+		require.Equal(t, http.StatusOK, w.Code, "request %d", i)
+		require.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		require.Equal(t, strconv.Itoa(remaining), w.Header().Get("RateLimit-Remaining"))
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.1:5001"))
+
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	require.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.InDelta(t, 60, retryAfter, 1)
+
+	reset, err := strconv.Atoi(w.Header().Get("RateLimit-Reset"))
+	require.NoError(t, err)
+	require.InDelta(t, 120, reset, 1)
+
+	// other clients have buckets of their own
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, request("10.0.0.2:5000"))
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestKey(t *testing.T) {
+	r := request("10.0.0.1:5000")
+	require.Equal(t, "ip:10.0.0.1", mwRateLimit.Key(r))
+
+	id := uuid.New()
+	r = r.WithContext(mwAuth.WithPrincipal(context.Background(), mwAuth.Principal{UserId: id}))
+	require.Equal(t, "user:"+id.String(), mwRateLimit.Key(r))
+}
+
+func TestCaller(t *testing.T) {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	jwt := authSvc.New(nil, cfg, h)
+
+	token := func() string {
+		token, err := jwt.GenerateJwtToken(userDto.Model{Id: uuid.New(), Email: "aibek@inai.kg", AccessLevel: 50}, uuid.New())
+		require.NoError(t, err)
+		return token
+	}
+
+	handler := mwRateLimit.Caller(mwRateLimit.New(1, 1, 0), mwRateLimit.New(1, 1, 0), cfg.JwtSecret)(ok)
+
+	// students behind the campus NAT
+	do := func(token string) int {
+		r := request("10.0.0.1:5000")
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	first, second := token(), token()
+	require.Equal(t, http.StatusOK, do(first))
+	require.Equal(t, http.StatusOK, do(second))
+	require.Equal(t, http.StatusTooManyRequests, do(first))
+
+	// anonymous and forged tokens share the address bucket
+	require.Equal(t, http.StatusOK, do(""))
+	require.Equal(t, http.StatusTooManyRequests, do("forged"))
+}
+
+func TestMaxKeys(t *testing.T) {
+	l := mwRateLimit.New(1, 1, 3)
+
+	for i := range 100 {
+		l.Allow("ip:10.0.0." + strconv.Itoa(i))
+	}
+	require.Equal(t, 3, l.Len())
+
+	require.False(t, l.Allow("ip:10.0.0.99").Allowed)
+
+	// evicted clients start over
+	require.True(t, l.Allow("ip:10.0.0.1").Allowed)
+	require.Equal(t, 3, l.Len())
+}
+
+func TestDisabled(t *testing.T) {
+	h := mwRateLimit.Limit(nil)(ok)
+
+	for range 10 {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request("10.0.0.1:5000"))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Empty(t, w.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	trusted, err := mwRealIp.ParseTrusted([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	_, err = mwRealIp.ParseTrusted([]string{"proxy"})
+	require.Error(t, err)
+
+	tests := []struct {
+		name   string
+		remote string
+		xff    []string
+		want   string
+	}{
+		{name: "direct client", remote: "203.0.113.7:4000", want: "203.0.113.7:4000"},
+		{name: "untrusted peer can't spoof", remote: "203.0.113.7:4000", xff: []string{"1.2.3.4"}, want: "203.0.113.7:4000"},
+		{name: "trusted proxy", remote: "10.1.2.3:4000", xff: []string{"198.51.100.9"}, want: "198.51.100.9"},
+		{name: "forged left entry", remote: "10.1.2.3:4000", xff: []string{"1.2.3.4, 198.51.100.9"}, want: "198.51.100.9"},
+		{name: "proxy chain", remote: "10.1.2.3:4000", xff: []string{"198.51.100.9, 192.168.1.1", "10.9.9.9"}, want: "198.51.100.9"},
+		{name: "no header", remote: "10.1.2.3:4000", want: "10.1.2.3:4000"},
+		{name: "garbage", remote: "10.1.2.3:4000", xff: []string{"unknown"}, want: "10.1.2.3:4000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := mwRealIp.RealIP(trusted)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			r := request(tt.remote)
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package router_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	mwChain "new-version/internal/http/middleware/chain"
+	"new-version/internal/http/router"
+
+	"github.com/stretchr/testify/require"
+)
+
+// trace appends name to the X-Trace response header before calling next.
+func trace(name string) mwChain.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Trace", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func serve(h http.Handler, method string, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.Header().Add("X-Trace", "handler") }
+
+	rt := router.New()
+	rt.Use(trace("global"))
+	rt.HandleFunc("GET /public", ok)
+
+	admin := rt.Group(trace("auth"))
+	admin.HandleFunc("GET /admin", ok)
+	admin.Group(trace("csrf")).HandleFunc("DELETE /admin", ok, trace("route"))
+
+	h := rt.Handler()
+
+	tests := []struct {
+		method string
+		path   string
+		status int
+		trace  string
+	}{
+		{method: http.MethodGet, path: "/public", status: http.StatusOK, trace: "global,handler"},
+		{method: http.MethodGet, path: "/admin", status: http.StatusOK, trace: "global,auth,handler"},
+		{method: http.MethodDelete, path: "/admin", status: http.StatusOK, trace: "global,auth,csrf,route,handler"},
+		{method: http.MethodGet, path: "/missing", status: http.StatusNotFound, trace: "global"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			w := serve(h, tt.method, tt.path)
+
+			require.Equal(t, tt.status, w.Code)
+			require.Equal(t, tt.trace, strings.Join(w.Header().Values("X-Trace"), ","))
+		})
+	}
+}
+
+func TestGroupDoesNotLeak(t *testing.T) {
+	rt := router.New()
+
+	base := rt.Group(trace("a"))
+	_ = base.Group(trace("b"))
+	base.HandleFunc("GET /x", func(http.ResponseWriter, *http.Request) {})
+
+	w := serve(rt.Handler(), http.MethodGet, "/x")
+	require.Equal(t, []string{"a"}, w.Header().Values("X-Trace"))
+}
@@ -0,0 +1,51 @@
+package router_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"new-version/internal/http/router"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	deprecated := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	rt := router.New()
+	handlers := func(g *router.Group) {
+		g.HandleFunc("GET /user/sessions", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Pattern", r.Pattern)
+		}, trace("route"))
+		g.Mount("/admin", trace("admin")).HandleFunc("/audit", func(http.ResponseWriter, *http.Request) {})
+	}
+
+	handlers(rt.Version(router.Version{Prefix: "/api/v2"}))
+	handlers(rt.Version(router.Version{Deprecated: deprecated, Sunset: sunset, Successor: "/api/v2"}, trace("legacy")))
+
+	h := rt.Handler()
+
+	w := serve(h, http.MethodGet, "/api/v2/user/sessions")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "GET /api/v2/user/sessions", w.Header().Get("X-Pattern"))
+	require.Equal(t, []string{"route"}, w.Header().Values("X-Trace"))
+	require.Empty(t, w.Header().Get("Deprecation"))
+	require.Empty(t, w.Header().Get("Sunset"))
+
+	w = serve(h, http.MethodGet, "/user/sessions")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "GET /user/sessions", w.Header().Get("X-Pattern"))
+	require.Equal(t, []string{"legacy", "route"}, w.Header().Values("X-Trace"))
+	require.Equal(t, "@1767225600", w.Header().Get("Deprecation"))
+	require.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", w.Header().Get("Sunset"))
+	require.Equal(t, `</api/v2/user/sessions>; rel="successor-version"`, w.Header().Get("Link"))
+
+	w = serve(h, http.MethodPost, "/api/v2/admin/audit")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, []string{"admin"}, w.Header().Values("X-Trace"))
+
+	w = serve(h, http.MethodGet, "/api/v1/user/sessions")
+	require.Equal(t, http.StatusNotFound, w.Code)
+}
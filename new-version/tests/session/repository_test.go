@@ -0,0 +1,62 @@
+package session_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	sessionRepo "new-version/internal/repository/session"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+var columns = []string{"id", "user_id", "user_agent", "ip", "created_at", "last_seen_at", "expires_at", "revoked_at"}
+
+func TestSessionRepository_GetById(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := sessionRepo.New(db)
+
+	id, userId := uuid.New(), uuid.New()
+	tn := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM sessions WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnRows(mock.NewRows(columns).AddRow(id, userId, "curl/8.0", "10.0.0.7", tn, tn, tn.Add(time.Hour), tn))
+
+	sess, err := repo.GetById(context.Background(), id)
+	require.NoError(t, err)
+	require.Equal(t, userId, sess.UserId)
+	require.NotNil(t, sess.RevokedAt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepository_Revoke(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := sessionRepo.New(db)
+
+	id, userId := uuid.New(), uuid.New()
+	query := regexp.QuoteMeta(`UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`)
+
+	mock.ExpectExec(query).
+		WithArgs(sqlmock.AnyArg(), id, userId).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, repo.Revoke(context.Background(), id, userId))
+
+	// another user's or an already revoked session
+	mock.ExpectExec(query).
+		WithArgs(sqlmock.AnyArg(), id, userId).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.Error(t, repo.Revoke(context.Background(), id, userId))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
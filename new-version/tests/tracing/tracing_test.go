@@ -0,0 +1,95 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"new-version/internal/config"
+	"new-version/internal/http/router"
+	bookCatRepo "new-version/internal/repository/bookcategory"
+	bookCatSvc "new-version/internal/service/bookcategory"
+	"new-version/internal/tracing"
+	"new-version/pkg/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const (
+	traceId  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentId = "00f067aa0ba902b7"
+)
+
+func TestTracing(t *testing.T) {
+	_, err := tracing.Setup(context.Background(), &config.Tracing{})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var buf bytes.Buffer
+	log := slog.New(logger.NewContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	rt := router.New()
+	rt.Use(tracing.Middleware, tracing.Route)
+	rt.HandleFunc("GET /books/{id}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "service.book.GetById")
+		defer span.End()
+
+		log.InfoContext(ctx, "fetching book")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/books/1", nil)
+	r.Header.Set("traceparent", "00-"+traceId+"-"+parentId+"-01")
+	rt.Handler().ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+
+	service, server := spans[0], spans[1]
+	require.Equal(t, "service.book.GetById", service.Name())
+	require.Equal(t, "GET /books/{id}", server.Name())
+
+	require.Equal(t, traceId, server.SpanContext().TraceID().String())
+	require.Equal(t, parentId, server.Parent().SpanID().String())
+	require.Equal(t, server.SpanContext().SpanID(), service.Parent().SpanID())
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	require.Equal(t, traceId, rec[logger.KeyTraceID])
+	require.Equal(t, service.SpanContext().SpanID().String(), rec[logger.KeySpanID])
+}
+
+func TestFailedSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM book_categories").WillReturnError(errors.New("connection refused"))
+
+	svc := bookCatSvc.New(nil, bookCatRepo.New(db), nil)
+	_, err = svc.GetById(context.Background(), 1)
+	require.Error(t, err)
+
+	spans := recorder.Ended()
+	require.NotEmpty(t, spans)
+
+	span := spans[len(spans)-1]
+	require.Equal(t, "service.bookcategory.GetById", span.Name())
+	require.Equal(t, codes.Error, span.Status().Code)
+	require.Len(t, span.Events(), 1)
+	require.Equal(t, "exception", span.Events()[0].Name)
+}
@@ -0,0 +1,142 @@
+package user_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	sessionDto "new-version/internal/contract/session"
+	userDto "new-version/internal/contract/user"
+	"new-version/internal/http/cookie"
+	userHdl "new-version/internal/http/handler/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	mwCsrf "new-version/internal/http/middleware/csrf"
+	mwRateLimit "new-version/internal/http/middleware/ratelimit"
+	mwRequestId "new-version/internal/http/middleware/requestid"
+	"new-version/internal/http/router"
+	authSvc "new-version/internal/service/auth"
+	"new-version/pkg/errs"
+	"new-version/pkg/hasher"
+	hp "new-version/pkg/httphelpers"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// sessions keeps the ids of revoked sessions.
+type sessions struct {
+	revoked map[uuid.UUID]bool
+}
+
+func (s *sessions) Create(context.Context, uuid.UUID, time.Duration) (uuid.UUID, error) {
+	return uuid.New(), nil
+}
+
+func (s *sessions) List(context.Context, uuid.UUID, uuid.UUID) ([]sessionDto.Response, error) {
+	return nil, nil
+}
+
+func (s *sessions) Revoke(_ context.Context, _ uuid.UUID, id uuid.UUID) error {
+	s.revoked[id] = true
+	return nil
+}
+
+func (s *sessions) RevokeAll(context.Context, uuid.UUID) error { return nil }
+
+func (s *sessions) Validate(context.Context, uuid.UUID) error { return nil }
+
+// users keeps the context of the last call and rejects every login.
+type users struct {
+	ctx context.Context
+}
+
+func (u *users) Register(ctx context.Context, _ userDto.Request) error {
+	u.ctx = ctx
+	return nil
+}
+
+func (u *users) Login(ctx context.Context, _ userDto.Request) (string, error) {
+	u.ctx = ctx
+	return "", errs.Unauthorized("wrong email or password")
+}
+
+func TestRequestContext(t *testing.T) {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	jar, err := cookie.New(&config.Cookie{Secure: true, SameSite: "lax"})
+	require.NoError(t, err)
+
+	// the span tracing.Middleware starts for the request
+	server := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	svc := &users{}
+	mw := &router.Middlewares{
+		Auth:          mwAuth.New(cfg.JwtSecret, &sessions{}),
+		Csrf:          mwCsrf.Csrf(cfg.JwtSecret),
+		RateLimit:     mwRateLimit.Limit(nil),
+		AuthRateLimit: mwRateLimit.Limit(nil),
+	}
+
+	rt := router.New()
+	userHdl.New(nil, svc, &sessions{}, cfg, jar).RegisterRoutes(rt.Group(), mw)
+	handler := mwRequestId.RequestID(rt.Handler())
+
+	for _, path := range []string{"/user/register", "/user/login"} {
+		t.Run(path, func(t *testing.T) {
+			svc.ctx = nil
+
+			r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"email": "aibek@inai.kg", "pass_hash": "Kitap-2024!"}`))
+			r.Header.Set(hp.HeaderRequestID, "req-42")
+			r = r.WithContext(trace.ContextWithSpanContext(r.Context(), server))
+
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			require.NotNil(t, svc.ctx)
+			require.Equal(t, "req-42", hp.RequestID(svc.ctx))
+			require.Equal(t, server, trace.SpanContextFromContext(svc.ctx), "service spans must be children of the server span")
+		})
+	}
+}
+
+func TestLogoutWithBearerToken(t *testing.T) {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	sessionId := uuid.New()
+	token, err := authSvc.New(nil, cfg, h).GenerateJwtToken(userDto.Model{Id: uuid.New(), Email: "aibek@inai.kg", AccessLevel: 50}, sessionId)
+	require.NoError(t, err)
+
+	jar, err := cookie.New(&config.Cookie{Secure: true, SameSite: "lax"})
+	require.NoError(t, err)
+
+	svc := &sessions{revoked: map[uuid.UUID]bool{}}
+	mw := &router.Middlewares{
+		Auth:          mwAuth.New(cfg.JwtSecret, svc),
+		Csrf:          mwCsrf.Csrf(cfg.JwtSecret),
+		RateLimit:     mwRateLimit.Limit(nil),
+		AuthRateLimit: mwRateLimit.Limit(nil),
+	}
+
+	rt := router.New()
+	userHdl.New(nil, nil, svc, cfg, jar).RegisterRoutes(rt.Group(), mw)
+
+	r := httptest.NewRequest(http.MethodPost, "/user/logout", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+
+	w := httptest.NewRecorder()
+	rt.Handler().ServeHTTP(w, r)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.True(t, svc.revoked[sessionId])
+}
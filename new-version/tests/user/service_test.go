@@ -0,0 +1,97 @@
+package user_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"new-version/internal/config"
+	auditDto "new-version/internal/contract/audit"
+	userDto "new-version/internal/contract/user"
+	mwAuth "new-version/internal/http/middleware/auth"
+	userRepo "new-version/internal/repository/user"
+	auditSvc "new-version/internal/service/audit"
+	authSvc "new-version/internal/service/auth"
+	userSvc "new-version/internal/service/user"
+	"new-version/pkg/hasher"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// directoryUser authenticates every login as identity.
+type directoryUser struct {
+	identity authSvc.Identity
+}
+
+func (d *directoryUser) Authenticate(context.Context, string, string) (authSvc.Identity, error) {
+	return d.identity, nil
+}
+
+// events keeps the recorded audit events.
+type events struct {
+	recorded []auditDto.Event
+}
+
+func (e *events) Record(_ context.Context, event auditDto.Event) {
+	e.recorded = append(e.recorded, event)
+}
+
+func (e *events) List(context.Context, auditDto.Filter) ([]auditDto.Event, error) {
+	return e.recorded, nil
+}
+
+func TestLoginExternalKeepsLocalRole(t *testing.T) {
+	cfg := &config.Security{JwtSecret: "test-secret", AccessTokenExpire: time.Hour}
+
+	h, err := hasher.New(hasher.Options{Algorithm: hasher.AlgBcrypt, BcryptCost: 4})
+	require.NoError(t, err)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	audit := &events{}
+	svc := userSvc.New(
+		nil,
+		userRepo.New(db),
+		authSvc.New(nil, cfg, h),
+		&directoryUser{identity: authSvc.Identity{Email: " Aibek@INAI.kg ", AccessLevel: 100, Source: authSvc.SourceLDAP}},
+		&sessions{revoked: map[uuid.UUID]bool{}},
+		audit,
+		nil,
+		nil,
+		cfg,
+	)
+
+	id := uuid.New()
+	columns := []string{"id", "email", "joined_at", "access_level"}
+	query := regexp.QuoteMeta(`SELECT id, email, joined_at, access_level FROM users WHERE email = $1`)
+
+	mock.ExpectQuery(query).
+		WithArgs("aibek@inai.kg").
+		WillReturnRows(mock.NewRows(columns).AddRow(id, "aibek@inai.kg", time.Now(), 50))
+	mock.ExpectExec(regexp.QuoteMeta(`ON CONFLICT (email) DO UPDATE SET access_level = EXCLUDED.access_level
+		WHERE users.pass_hash = '!'`)).
+		WithArgs(sqlmock.AnyArg(), "aibek@inai.kg", "!", 100).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(query).
+		WithArgs("aibek@inai.kg").
+		WillReturnRows(mock.NewRows(columns).AddRow(id, "aibek@inai.kg", time.Now(), 50))
+
+	token, err := svc.Login(context.Background(), userDto.Request{Email: "Aibek@INAI.kg", Password: "Secret1!"})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	p, err := mwAuth.PrincipalFromToken(token, cfg.JwtSecret)
+	require.NoError(t, err)
+	require.Equal(t, id, p.UserId)
+	require.Equal(t, "aibek@inai.kg", p.Email)
+	require.EqualValues(t, 50, p.AccessLevel)
+
+	for _, event := range audit.recorded {
+		require.NotEqual(t, auditSvc.ActionRoleChange, event.Action)
+	}
+}
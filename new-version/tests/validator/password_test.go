@@ -0,0 +1,105 @@
+package validator_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"new-version/internal/config"
+	userVal "new-version/internal/validator/user"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newPolicyConfig() *config.PasswordPolicy {
+	return &config.PasswordPolicy{
+		MinLen:             8,
+		MaxLen:             64,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSpecial:     true,
+		MaxRepeated:        3,
+		RejectEmailSimilar: true,
+	}
+}
+
+func TestPasswordPolicy_Valid(t *testing.T) {
+	policy, err := userVal.NewPasswordPolicy(newPolicyConfig())
+	require.NoError(t, err)
+
+	require.Empty(t, policy.Validate("Kitap-2024!", "aibek@inai.kg"))
+	require.Empty(t, policy.Validate("Китеп-2024!", "aibek@inai.kg"))
+}
+
+func TestPasswordPolicy_ListsAllFailedRules(t *testing.T) {
+	policy, err := userVal.NewPasswordPolicy(newPolicyConfig())
+	require.NoError(t, err)
+
+	failed := policy.Validate("aaaa", "aibek@inai.kg")
+	require.Equal(t, []string{
+		userVal.TooShort(8),
+		userVal.HasNoNumber(),
+		userVal.HasNoCapitalizedLetter(),
+		userVal.HasNoSpecialSymbol(),
+		userVal.TooManyRepeated(3),
+	}, failed)
+
+	for _, msg := range failed {
+		require.NotContains(t, msg, "aaaa")
+	}
+}
+
+func TestPasswordPolicy_Rules(t *testing.T) {
+	policy, err := userVal.NewPasswordPolicy(newPolicyConfig())
+	require.NoError(t, err)
+
+	tests := []struct {
+		pass string
+		want string
+	}{
+		{pass: "Aa1!" + strings.Repeat("xyz", 21), want: userVal.TooLong(64)},
+		{pass: "Kitap-2024!" + strings.Repeat("кд", 20), want: userVal.TooManyBytes()},
+		{pass: "kitap-2024!", want: userVal.HasNoCapitalizedLetter()},
+		{pass: "KITAP-2024!", want: userVal.HasNoLowercaseLetter()},
+		{pass: "Kitap-kitap!", want: userVal.HasNoNumber()},
+		{pass: "Kitap20245", want: userVal.HasNoSpecialSymbol()},
+		{pass: "Kitap-20000!", want: userVal.TooManyRepeated(3)},
+		{pass: "Aibek-2024!", want: userVal.SimilarToEmail()},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, []string{tt.want}, policy.Validate(tt.pass, "aibek@inai.kg"), tt.pass)
+	}
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	sum := sha1.Sum([]byte("Library-2024!"))
+
+	list := strings.Join([]string{
+		"# leaked passwords",
+		"Password1!",
+		strings.ToUpper(hex.EncodeToString(sum[:])) + ":42",
+		"",
+	}, "\n")
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(list), 0o600))
+
+	cfg := newPolicyConfig()
+	cfg.BreachedListPath = path
+
+	policy, err := userVal.NewPasswordPolicy(cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{userVal.Breached()}, policy.Validate("Password1!", "aibek@inai.kg"))
+	require.Equal(t, []string{userVal.Breached()}, policy.Validate("Library-2024!", "aibek@inai.kg"))
+	require.Empty(t, policy.Validate("Kitap-2024!", "aibek@inai.kg"))
+
+	cfg.BreachedListPath = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = userVal.NewPasswordPolicy(cfg)
+	require.Error(t, err)
+}
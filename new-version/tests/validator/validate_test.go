@@ -0,0 +1,131 @@
+package validator_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	bookCatDto "new-version/internal/contract/bookcategory"
+	legacyDto "new-version/internal/contract/legacy"
+	userDto "new-version/internal/contract/user"
+	impersonationHdl "new-version/internal/http/handler/impersonation"
+	"new-version/internal/validator/common"
+	"new-version/pkg/errs"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bookRequest struct {
+	Title       string   `json:"title" validate:"required,max=10"`
+	ISBN        string   `json:"isbn" validate:"isbn"`
+	Language    string   `json:"language" validate:"required,oneof=Кыргызский|Русский|Английский|Немецкий"`
+	EditionYear int      `json:"edition_year" validate:"required,min=1450,notfuture"`
+	Email       string   `json:"email" validate:"email"`
+	Tags        []string `json:"tags" validate:"max=2"`
+	Note        string
+}
+
+func TestValidate_Valid(t *testing.T) {
+	req := bookRequest{
+		Title:       "Манас",
+		ISBN:        "978-5-17-118366-0",
+		Language:    "Кыргызский",
+		EditionYear: 2020,
+	}
+
+	require.NoError(t, common.Validate(&req))
+}
+
+func TestValidate_ReportsEveryField(t *testing.T) {
+	req := bookRequest{
+		Title:       "Джамиля и другие повести",
+		ISBN:        "978-5-17-118366-7",
+		Language:    "Французский",
+		EditionYear: time.Now().Year() + 1,
+		Email:       "aibek",
+		Tags:        []string{"a", "b", "c"},
+	}
+
+	err := common.Validate(&req)
+	require.ErrorIs(t, err, errs.ErrValidation)
+	require.Equal(t, []errs.FieldError{
+		{Field: "title", Message: "must have at most 10 characters"},
+		{Field: "isbn", Message: "must be a valid ISBN"},
+		{Field: "language", Message: "must be one of: Кыргызский, Русский, Английский, Немецкий"},
+		{Field: "edition_year", Message: "can't be later than the current year"},
+		{Field: "email", Message: "must be a valid email address"},
+		{Field: "tags", Message: "must have at most 2 items"},
+	}, errs.Fields(err))
+}
+
+func TestValidate_Required(t *testing.T) {
+	err := common.Validate(&bookRequest{})
+	require.Equal(t, []errs.FieldError{
+		{Field: "title", Message: "is required"},
+		{Field: "language", Message: "is required"},
+		{Field: "edition_year", Message: "is required"},
+	}, errs.Fields(err))
+}
+
+// TestCheckRules_Requests catches typos in the tags of the request types
+// before a request does.
+func TestCheckRules_Requests(t *testing.T) {
+	for _, req := range []any{
+		&bookRequest{},
+		&bookCatDto.Request{},
+		&userDto.Request{},
+		&legacyDto.Credentials{},
+		&legacyDto.CategoryRequest{},
+		&impersonationHdl.StartRequest{},
+	} {
+		require.NoError(t, common.CheckRules(req), "%T", req)
+	}
+}
+
+func TestValidate_BadRules(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "unknown rule", v: &struct {
+			Title string `validate:"requird"`
+		}{Title: "Манас"}},
+		{name: "bad argument", v: &struct {
+			Title string `validate:"max=ten"`
+		}{Title: "Манас"}},
+		{name: "min on bool", v: &struct {
+			Active bool `validate:"min=1"`
+		}{Active: true}},
+		{name: "email on int", v: &struct {
+			Email int `validate:"email"`
+		}{}},
+		{name: "not a struct", v: "Манас"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, common.CheckRules(tt.v))
+
+			var err error
+			require.NotPanics(t, func() { err = common.Validate(tt.v) })
+			require.Error(t, err)
+			require.NotErrorIs(t, err, errs.ErrValidation)
+		})
+	}
+}
+
+func TestIsEmail(t *testing.T) {
+	require.True(t, common.IsEmail("aibek@inai.kg"))
+	require.False(t, common.IsEmail("aibek"))
+	require.False(t, common.IsEmail("Aibek <aibek@inai.kg>"))
+}
+
+func TestIsISBN(t *testing.T) {
+	for _, isbn := range []string{"0-306-40615-2", "080442957X", "978 0 306 40615 7", "9780306406157"} {
+		require.True(t, common.IsISBN(isbn), isbn)
+	}
+
+	for _, isbn := range []string{"0-306-40615-3", "97803064061", "978030640615X", "", strconv.Itoa(1234567890)} {
+		require.False(t, common.IsISBN(isbn), isbn)
+	}
+}